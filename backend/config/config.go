@@ -8,8 +8,10 @@ import (
 
 type Config struct {
 	App struct {
-		Name string `mapstructure:"name"`
-		Port string `mapstructure:"port"`
+		Name           string   `mapstructure:"name"`
+		Port           string   `mapstructure:"port"`
+		FrontendURL    string   `mapstructure:"frontend_url"`
+		TrustedProxies []string `mapstructure:"trusted_proxies"`
 	} `mapstructure:"app"`
 	Database struct {
 		DSN          string `mapstructure:"dsn"`
@@ -17,14 +19,81 @@ type Config struct {
 		MaxOpenConns int    `mapstructure:"max_open_conns"`
 	} `mapstructure:"database"`
 	Auth struct {
-		JWTSecret     string `mapstructure:"jwt_secret"`
-		TokenTTLHours int    `mapstructure:"token_ttl_hours"`
+		JWTSecret                 string   `mapstructure:"jwt_secret"`
+		AccessTokenTTLMinutes     int      `mapstructure:"access_token_ttl_minutes"`
+		RefreshTokenTTLHours      int      `mapstructure:"refresh_token_ttl_hours"`
+		DefaultRole               string   `mapstructure:"default_role"`
+		AdminUsernames            []string `mapstructure:"admin_usernames"`
+		PasswordResetTTLMinutes   int      `mapstructure:"password_reset_ttl_minutes"`
+		EmailVerificationTTLHours int      `mapstructure:"email_verification_ttl_hours"`
+		LoginMaxAttempts          int      `mapstructure:"login_max_attempts"`
+		LoginIPMaxAttempts        int      `mapstructure:"login_ip_max_attempts"`
+		LoginLockoutMinutes       int      `mapstructure:"login_lockout_minutes"`
+		LoginBackoffBaseSeconds   int      `mapstructure:"login_backoff_base_seconds"`
+		LoginBackoffMaxSeconds    int      `mapstructure:"login_backoff_max_seconds"`
 	} `mapstructure:"auth"`
+	Mail struct {
+		Driver    string `mapstructure:"driver"`
+		From      string `mapstructure:"from"`
+		OutboxDir string `mapstructure:"outbox_dir"`
+		SMTP      struct {
+			Host     string `mapstructure:"host"`
+			Port     int    `mapstructure:"port"`
+			Username string `mapstructure:"username"`
+			Password string `mapstructure:"password"`
+		} `mapstructure:"smtp"`
+	} `mapstructure:"mail"`
+	Posts struct {
+		RevisionLimit          int `mapstructure:"revision_limit"`
+		RevisionMaxAgeDays     int `mapstructure:"revision_max_age_days"`
+		PublishIntervalSeconds int `mapstructure:"publish_interval_seconds"`
+		UnlockTTLMinutes       int `mapstructure:"unlock_ttl_minutes"`
+		PreviewTTLHours        int `mapstructure:"preview_ttl_hours"`
+		PreviewMaxTTLHours     int `mapstructure:"preview_max_ttl_hours"`
+		TrashRetentionDays     int `mapstructure:"trash_retention_days"`
+	} `mapstructure:"posts"`
+	Comments struct {
+		MaxDepth          int    `mapstructure:"max_depth"`
+		Moderation        string `mapstructure:"moderation"`
+		EditWindowMinutes int    `mapstructure:"edit_window_minutes"`
+		RepliesPerThread  int    `mapstructure:"replies_per_thread"`
+		Spam              struct {
+			Enabled              bool     `mapstructure:"enabled"`
+			HoldScore            float64  `mapstructure:"hold_score"`
+			RejectScore          float64  `mapstructure:"reject_score"`
+			MaxLinks             int      `mapstructure:"max_links"`
+			BlockedWords         []string `mapstructure:"blocked_words"`
+			BlockedDomains       []string `mapstructure:"blocked_domains"`
+			BlockedIPs           []string `mapstructure:"blocked_ips"`
+			DuplicateWindowHours int      `mapstructure:"duplicate_window_hours"`
+			MaxPerMinute         int      `mapstructure:"max_per_minute"`
+		} `mapstructure:"spam"`
+	} `mapstructure:"comments"`
+	Search struct {
+		Driver     string `mapstructure:"driver"`
+		MaxResults int    `mapstructure:"max_results"`
+	} `mapstructure:"search"`
+	Slug struct {
+		Mode string `mapstructure:"mode"`
+	} `mapstructure:"slug"`
+	OIDC struct {
+		Providers []OIDCProviderConfig `mapstructure:"providers"`
+	} `mapstructure:"oidc"`
 	CORS struct {
 		AllowOrigins []string `mapstructure:"allow_origins"`
 	} `mapstructure:"cors"`
 }
 
+type OIDCProviderConfig struct {
+	Name         string   `mapstructure:"name"`
+	DisplayName  string   `mapstructure:"display_name"`
+	Issuer       string   `mapstructure:"issuer"`
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	RedirectURL  string   `mapstructure:"redirect_url"`
+	Scopes       []string `mapstructure:"scopes"`
+}
+
 var AppConfig *Config
 
 func InitConfig() {
@@ -33,7 +102,41 @@ func InitConfig() {
 	viper.AddConfigPath("./config")
 
 	viper.SetDefault("auth.jwt_secret", "change-me")
-	viper.SetDefault("auth.token_ttl_hours", 72)
+	viper.SetDefault("auth.access_token_ttl_minutes", 15)
+	viper.SetDefault("auth.refresh_token_ttl_hours", 720)
+	viper.SetDefault("auth.default_role", "author")
+	viper.SetDefault("auth.password_reset_ttl_minutes", 30)
+	viper.SetDefault("auth.email_verification_ttl_hours", 48)
+	viper.SetDefault("auth.login_max_attempts", 5)
+	viper.SetDefault("auth.login_ip_max_attempts", 20)
+	viper.SetDefault("auth.login_lockout_minutes", 15)
+	viper.SetDefault("auth.login_backoff_base_seconds", 1)
+	viper.SetDefault("auth.login_backoff_max_seconds", 60)
+	viper.SetDefault("app.frontend_url", "http://localhost:5173")
+	viper.SetDefault("posts.revision_limit", 50)
+	viper.SetDefault("posts.revision_max_age_days", 0)
+	viper.SetDefault("posts.publish_interval_seconds", 30)
+	viper.SetDefault("posts.unlock_ttl_minutes", 60)
+	viper.SetDefault("posts.preview_ttl_hours", 72)
+	viper.SetDefault("posts.preview_max_ttl_hours", 720)
+	viper.SetDefault("posts.trash_retention_days", 30)
+	viper.SetDefault("comments.max_depth", 5)
+	viper.SetDefault("comments.moderation", "hold_guests")
+	viper.SetDefault("comments.edit_window_minutes", 15)
+	viper.SetDefault("comments.replies_per_thread", 20)
+	viper.SetDefault("comments.spam.enabled", true)
+	viper.SetDefault("comments.spam.hold_score", 3)
+	viper.SetDefault("comments.spam.reject_score", 6)
+	viper.SetDefault("comments.spam.max_links", 2)
+	viper.SetDefault("comments.spam.duplicate_window_hours", 168)
+	viper.SetDefault("comments.spam.max_per_minute", 3)
+	viper.SetDefault("slug.mode", "transliterate")
+	viper.SetDefault("search.driver", "mysql")
+	viper.SetDefault("search.max_results", 1000)
+	viper.SetDefault("mail.driver", "log")
+	viper.SetDefault("mail.from", "no-reply@localhost")
+	viper.SetDefault("mail.outbox_dir", "./outbox")
+	viper.SetDefault("mail.smtp.port", 587)
 
 	if err := viper.ReadInConfig(); err != nil {
 		log.Fatalf("Error reading config file: %v", err)
@@ -46,4 +149,8 @@ func InitConfig() {
 	}
 
 	InitDB()
+	InitMailer()
+	InitOIDC()
+	InitSearch()
+	InitSpam()
 }
@@ -34,7 +34,126 @@ func InitDB() {
 		&models.Tag{},
 		&models.Post{},
 		&models.Comment{},
+		&models.Session{},
+		&models.UserToken{},
+		&models.PersonalAccessToken{},
+		&models.LoginThrottle{},
+		&models.RecoveryCode{},
+		&models.UserIdentity{},
+		&models.PostRevision{},
+		&models.SlugRedirect{},
+		&models.PostPreview{},
+		&models.PostPreviewView{},
+		&models.PostTransition{},
 	); err != nil {
 		log.Fatalf("Failed to run database migrations: %v", err)
 	}
+
+	if err := renderLegacyPosts(db); err != nil {
+		log.Fatalf("Failed to render post content: %v", err)
+	}
+
+	if err := migrateCommentApproval(db); err != nil {
+		log.Fatalf("Failed to migrate comment statuses: %v", err)
+	}
+
+	if err := backfillCommentRoots(db); err != nil {
+		log.Fatalf("Failed to backfill comment threads: %v", err)
+	}
+
+	if len(AppConfig.Auth.AdminUsernames) > 0 {
+		if err := db.Model(&models.User{}).
+			Where("username IN ?", AppConfig.Auth.AdminUsernames).
+			Update("role", models.RoleAdmin).Error; err != nil {
+			log.Fatalf("Failed to promote admin users: %v", err)
+		}
+	}
+}
+
+// renderLegacyPosts fills ContentHTML for posts stored before content was
+// rendered on save.
+func renderLegacyPosts(db *gorm.DB) error {
+	var posts []models.Post
+	return db.Unscoped().
+		Select("id", "content").
+		Where("(content_html IS NULL OR content_html = '') AND content <> ''").
+		FindInBatches(&posts, 100, func(tx *gorm.DB, batch int) error {
+			for i := range posts {
+				if err := posts[i].RenderContent(); err != nil {
+					return err
+				}
+				if err := db.Unscoped().Model(&posts[i]).Select("content_html", "toc").UpdateColumns(&posts[i]).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// migrateCommentApproval carries the old Approved flag over to Status, which
+// replaced it, and drops the column.
+func migrateCommentApproval(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasColumn(&models.Comment{}, "approved") {
+		return nil
+	}
+	if err := db.Unscoped().Model(&models.Comment{}).
+		Where("approved = ?", false).
+		UpdateColumn("status", models.CommentStatusPending).Error; err != nil {
+		return err
+	}
+	return migrator.DropColumn(&models.Comment{}, "approved")
+}
+
+// backfillCommentRoots fills RootID for replies stored before it existed.
+func backfillCommentRoots(db *gorm.DB) error {
+	var missing int64
+	if err := db.Unscoped().Model(&models.Comment{}).
+		Where("parent_id IS NOT NULL AND root_id IS NULL").
+		Count(&missing).Error; err != nil || missing == 0 {
+		return err
+	}
+
+	var comments []struct {
+		ID       uint
+		ParentID *uint
+	}
+	if err := db.Unscoped().Model(&models.Comment{}).
+		Select("id", "parent_id").
+		Find(&comments).Error; err != nil {
+		return err
+	}
+
+	parents := make(map[uint]uint, len(comments))
+	for _, comment := range comments {
+		if comment.ParentID != nil {
+			parents[comment.ID] = *comment.ParentID
+		}
+	}
+
+	threads := make(map[uint][]uint)
+	for id := range parents {
+		root := id
+		// A missing parent ends the walk; the seen check guards against
+		// corrupt cycles.
+		for seen := 0; seen <= len(parents); seen++ {
+			parent, ok := parents[root]
+			if !ok {
+				break
+			}
+			root = parent
+		}
+		threads[root] = append(threads[root], id)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for root, ids := range threads {
+			if err := tx.Unscoped().Model(&models.Comment{}).
+				Where("id IN ?", ids).
+				UpdateColumn("root_id", root).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"log"
+	"strings"
+
+	"gogogo/global"
+	"gogogo/mailer"
+)
+
+func InitMailer() {
+	mailConfig := AppConfig.Mail
+
+	switch strings.ToLower(mailConfig.Driver) {
+	case "smtp":
+		global.Mailer = mailer.NewSMTPMailer(
+			mailConfig.SMTP.Host,
+			mailConfig.SMTP.Port,
+			mailConfig.SMTP.Username,
+			mailConfig.SMTP.Password,
+			mailConfig.From,
+		)
+	case "file":
+		fileMailer, err := mailer.NewFileMailer(mailConfig.OutboxDir, mailConfig.From)
+		if err != nil {
+			log.Fatalf("Failed to initialize mail outbox: %v", err)
+		}
+		global.Mailer = fileMailer
+	default:
+		global.Mailer = mailer.NewLogMailer(mailConfig.From)
+	}
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"log"
+
+	"gogogo/global"
+	"gogogo/oidc"
+)
+
+func InitOIDC() {
+	providers := make([]oidc.ProviderConfig, 0, len(AppConfig.OIDC.Providers))
+	for _, provider := range AppConfig.OIDC.Providers {
+		providers = append(providers, oidc.ProviderConfig{
+			Name:         provider.Name,
+			DisplayName:  provider.DisplayName,
+			Issuer:       provider.Issuer,
+			ClientID:     provider.ClientID,
+			ClientSecret: provider.ClientSecret,
+			RedirectURL:  provider.RedirectURL,
+			Scopes:       provider.Scopes,
+		})
+	}
+
+	registry, err := oidc.NewRegistry(providers)
+	if err != nil {
+		log.Fatalf("Failed to configure OIDC providers: %v", err)
+	}
+	global.OIDC = registry
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"log"
+	"strings"
+
+	"gogogo/global"
+	"gogogo/search"
+)
+
+func InitSearch() {
+	switch strings.ToLower(AppConfig.Search.Driver) {
+	case "memory":
+		engine := search.NewMemoryEngine()
+		global.Search = engine
+		if _, err := search.IndexAllPosts(global.Db, engine); err != nil {
+			log.Fatalf("Failed to build search index: %v", err)
+		}
+	default:
+		engine, err := search.NewMySQLEngine(global.Db)
+		if err != nil {
+			log.Fatalf("Failed to initialize search: %v", err)
+		}
+		global.Search = engine
+
+		// Sites upgrading to full-text search start with an empty index.
+		empty, err := engine.IsEmpty()
+		if err != nil {
+			log.Fatalf("Failed to initialize search: %v", err)
+		}
+		if empty {
+			if _, err := search.IndexAllPosts(global.Db, engine); err != nil {
+				log.Fatalf("Failed to build search index: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"log"
+
+	"gogogo/global"
+	"gogogo/spam"
+)
+
+// InitSpam sets up the comment spam filter. global.Spam stays nil when it is
+// disabled.
+func InitSpam() {
+	settings := AppConfig.Comments.Spam
+	if !settings.Enabled {
+		return
+	}
+
+	filter, err := spam.NewFilter(global.Db, spam.Config{
+		HoldScore:      settings.HoldScore,
+		RejectScore:    settings.RejectScore,
+		MaxLinks:       settings.MaxLinks,
+		BlockedWords:   settings.BlockedWords,
+		BlockedDomains: settings.BlockedDomains,
+		BlockedIPs:     settings.BlockedIPs,
+		MaxPerMinute:   settings.MaxPerMinute,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize spam filter: %v", err)
+	}
+	global.Spam = filter
+}
@@ -0,0 +1,336 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gogogo/config"
+	"gogogo/global"
+	"gogogo/mailer"
+	"gogogo/models"
+	"gogogo/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required"`
+}
+
+var errInvalidUserToken = errors.New("invalid or expired token")
+
+// ForgotPassword always answers 202 so the endpoint cannot be used to probe
+// which addresses are registered. Every request counts against the address
+// and the client IP like a failed login, whether or not a mail goes out, so
+// nobody can flood an inbox with reset links.
+func ForgotPassword(ctx *gin.Context) {
+	var input forgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	throttleKeys := passwordResetThrottleKeys(ctx, input.Email)
+	block, err := checkLoginAllowed(throttleKeys)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check reset requests"})
+		return
+	}
+	if block != nil {
+		seconds := int(math.Ceil(block.RetryAfter.Seconds()))
+		ctx.Header("Retry-After", strconv.Itoa(seconds))
+		ctx.JSON(http.StatusTooManyRequests, gin.H{
+			"error":      "too many reset requests, try again later",
+			"retryAfter": seconds,
+		})
+		return
+	}
+	if err := recordLoginFailure(throttleKeys); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record reset request"})
+		return
+	}
+
+	accepted := gin.H{"message": "if the email is registered, a reset link has been sent"}
+
+	var user models.User
+	if err := global.Db.Where("email = ?", strings.TrimSpace(input.Email)).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusAccepted, accepted)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load user"})
+		return
+	}
+
+	ttl := passwordResetTTL()
+	token, err := issueUserToken(user, models.TokenPurposePasswordReset, ttl)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create reset token"})
+		return
+	}
+
+	link := frontendLink("/reset-password", token)
+	sendMail(mailer.Message{
+		To:      *user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password for your account. "+
+			"Open the link below within %d minutes to choose a new one:\n\n%s\n\n"+
+			"If you did not request this, you can ignore this email.\n",
+			user.DisplayName, int(ttl.Minutes()), link),
+	})
+
+	ctx.JSON(http.StatusAccepted, accepted)
+}
+
+func ResetPassword(ctx *gin.Context) {
+	var input resetPasswordRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(input.NewPassword) < 6 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "password must be at least 6 characters"})
+		return
+	}
+
+	hashedPwd, err := utils.HashPassword(input.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
+		return
+	}
+
+	userToken, err := consumeUserToken(input.Token, models.TokenPurposePasswordReset)
+	if err != nil {
+		if errors.Is(err, errInvalidUserToken) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify token"})
+		return
+	}
+
+	if err := global.Db.Model(&models.User{}).
+		Where("id = ?", userToken.UserID).
+		Update("password", hashedPwd).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update password"})
+		return
+	}
+
+	if err := revokeUserSessions(userToken.UserID, 0); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke sessions"})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func VerifyEmail(ctx *gin.Context) {
+	userToken, err := consumeUserToken(ctx.Query("token"), models.TokenPurposeEmailVerification)
+	if err != nil {
+		if errors.Is(err, errInvalidUserToken) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify token"})
+		return
+	}
+
+	// The address may have changed since the link was sent; only the address
+	// the token was issued for can be confirmed by it.
+	result := global.Db.Model(&models.User{}).
+		Where("id = ? AND email = ?", userToken.UserID, userToken.Email).
+		Update("email_verified_at", time.Now())
+	if result.Error != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify email"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": errInvalidUserToken.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "email verified"})
+}
+
+func ResendVerificationEmail(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	user, err := loadUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load user"})
+		return
+	}
+
+	if user.Email == nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "no email address on file"})
+		return
+	}
+	if user.EmailVerifiedAt != nil {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "email already verified"})
+		return
+	}
+
+	if err := sendVerificationEmail(*user); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create verification token"})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"message": "verification email sent"})
+}
+
+func sendVerificationEmail(user models.User) error {
+	if user.Email == nil {
+		return nil
+	}
+
+	ttl := emailVerificationTTL()
+	token, err := issueUserToken(user, models.TokenPurposeEmailVerification, ttl)
+	if err != nil {
+		return err
+	}
+
+	link := frontendLink("/verify-email", token)
+	sendMail(mailer.Message{
+		To:      *user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\n"+
+			"The link expires in %d hours.\n",
+			user.DisplayName, link, int(ttl.Hours())),
+	})
+	return nil
+}
+
+// issueUserToken supersedes any outstanding token of the same purpose and
+// returns a new signed one.
+func issueUserToken(user models.User, purpose string, ttl time.Duration) (string, error) {
+	tokenID, err := utils.GenerateRandomToken(24)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	userToken := models.UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenID:   tokenID,
+		ExpiresAt: now.Add(ttl),
+	}
+	if user.Email != nil {
+		userToken.Email = *user.Email
+	}
+
+	err = global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.UserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", user.ID, purpose).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&userToken).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return utils.GenerateActionToken(purpose, user.ID, tokenID, ttl)
+}
+
+// consumeUserToken verifies the signature and marks the token used. The
+// conditional update makes concurrent redemptions of the same token fail.
+func consumeUserToken(token string, purpose string) (*models.UserToken, error) {
+	claims, err := utils.ValidateActionToken(strings.TrimSpace(token), purpose)
+	if err != nil {
+		return nil, errInvalidUserToken
+	}
+
+	userID, err := claims.SubjectID()
+	if err != nil {
+		return nil, errInvalidUserToken
+	}
+
+	var userToken models.UserToken
+	if err := global.Db.
+		Where("token_id = ? AND purpose = ? AND user_id = ?", claims.Id, purpose, userID).
+		First(&userToken).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errInvalidUserToken
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if userToken.UsedAt != nil || now.After(userToken.ExpiresAt) {
+		return nil, errInvalidUserToken
+	}
+
+	result := global.Db.Model(&models.UserToken{}).
+		Where("id = ? AND used_at IS NULL", userToken.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errInvalidUserToken
+	}
+
+	userToken.UsedAt = &now
+	return &userToken, nil
+}
+
+func passwordResetTTL() time.Duration {
+	ttl := config.AppConfig.Auth.PasswordResetTTLMinutes
+	if ttl <= 0 {
+		ttl = 30
+	}
+	return time.Duration(ttl) * time.Minute
+}
+
+func emailVerificationTTL() time.Duration {
+	ttl := config.AppConfig.Auth.EmailVerificationTTLHours
+	if ttl <= 0 {
+		ttl = 48
+	}
+	return time.Duration(ttl) * time.Hour
+}
+
+func frontendLink(path string, token string) string {
+	base := strings.TrimRight(config.AppConfig.App.FrontendURL, "/")
+	return base + path + "?token=" + url.QueryEscape(token)
+}
+
+// sendMail delivers msg and only logs failures: callers should not reveal
+// delivery problems to the client.
+// sendMail delivers msg in the background, so a slow mail server neither
+// holds up the request nor reveals by the delay that a mail was sent.
+func sendMail(msg mailer.Message) {
+	if global.Mailer == nil {
+		log.Printf("mailer not configured, dropping message to %s", msg.To)
+		return
+	}
+	go func(m mailer.Mailer) {
+		if err := m.Send(msg); err != nil {
+			log.Printf("failed to send mail to %s: %v", msg.To, err)
+		}
+	}(global.Mailer)
+}
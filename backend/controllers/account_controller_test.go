@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"gogogo/config"
+	"gogogo/global"
+	"gogogo/mailer"
+	"gogogo/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// blockingMailer hands every message to sent, but only once release is
+// closed, like an SMTP server that takes its time.
+type blockingMailer struct {
+	release chan struct{}
+	sent    chan mailer.Message
+}
+
+func (m *blockingMailer) Send(msg mailer.Message) error {
+	<-m.release
+	m.sent <- msg
+	return nil
+}
+
+func setupAccountTest(t *testing.T) (*gin.Engine, *blockingMailer) {
+	t.Helper()
+	setupTestDB(t, &models.User{}, &models.UserToken{}, &models.LoginThrottle{}, &models.Session{})
+
+	mail := &blockingMailer{release: make(chan struct{}), sent: make(chan mailer.Message, 10)}
+	global.Mailer = mail
+	t.Cleanup(func() { global.Mailer = nil })
+
+	router := gin.New()
+	router.POST("/api/auth/forgot-password", ForgotPassword)
+	router.POST("/api/auth/register", Register)
+	return router, mail
+}
+
+func TestForgotPasswordDoesNotWaitForMail(t *testing.T) {
+	router, mail := setupAccountTest(t)
+	email := "alice@example.com"
+	user := createTestUser(t, "alice", models.RoleAuthor)
+	global.Db.Model(&user).Update("email", email)
+
+	status, _ := serveJSON(t, router, http.MethodPost, "/api/auth/forgot-password", gin.H{"email": email})
+	if status != http.StatusAccepted {
+		t.Fatalf("status %d, want 202", status)
+	}
+
+	close(mail.release)
+	select {
+	case msg := <-mail.sent:
+		if msg.To != email || !strings.Contains(msg.Body, testFrontendURL+"/reset-password?token=") {
+			t.Fatalf("unexpected mail %+v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reset mail never sent")
+	}
+}
+
+func TestForgotPasswordThrottlesPerAddress(t *testing.T) {
+	router, mail := setupAccountTest(t)
+	close(mail.release)
+	config.AppConfig.Auth.LoginMaxAttempts = 2
+	config.AppConfig.Auth.LoginIPMaxAttempts = 10
+
+	// Unknown addresses are throttled the same way, so a 429 reveals nothing.
+	for i := 0; i < 2; i++ {
+		if status, _ := serveJSON(t, router, http.MethodPost, "/api/auth/forgot-password", gin.H{"email": "nobody@example.com"}); status != http.StatusAccepted {
+			t.Fatalf("request %d: status %d, want 202", i+1, status)
+		}
+	}
+	status, body := serveJSON(t, router, http.MethodPost, "/api/auth/forgot-password", gin.H{"email": "Nobody@Example.com"})
+	if status != http.StatusTooManyRequests {
+		t.Fatalf("third request: status %d, body %v, want 429", status, body)
+	}
+
+	if status, _ := serveJSON(t, router, http.MethodPost, "/api/auth/forgot-password", gin.H{"email": "other@example.com"}); status != http.StatusAccepted {
+		t.Fatalf("other address: status %d, want 202", status)
+	}
+
+	// The reset counters are separate from the login ones.
+	var loginKeys int64
+	global.Db.Model(&models.LoginThrottle{}).Where("throttle_key NOT LIKE ?", "reset:%").Count(&loginKeys)
+	if loginKeys != 0 {
+		t.Fatalf("%d login throttle keys touched by reset requests", loginKeys)
+	}
+}
+
+func TestRegisterRejectsInvalidEmail(t *testing.T) {
+	router, _ := setupAccountTest(t)
+
+	status, body := serveJSON(t, router, http.MethodPost, "/api/auth/register", gin.H{
+		"username": "alice",
+		"email":    "not an email",
+		"password": "secret-password",
+	})
+	if status != http.StatusBadRequest {
+		t.Fatalf("status %d, body %v, want 400", status, body)
+	}
+
+	var users int64
+	global.Db.Model(&models.User{}).Count(&users)
+	if users != 0 {
+		t.Fatalf("%d users created, want none", users)
+	}
+}
@@ -0,0 +1,381 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gogogo/global"
+	"gogogo/models"
+	"gogogo/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// UnlockUser clears failed-login counters and any lockout on the account.
+func UnlockUser(ctx *gin.Context) {
+	user, err := loadUserParam(ctx.Param("id"))
+	if err != nil {
+		handleUserLoadError(ctx, err)
+		return
+	}
+
+	if err := clearLoginFailures(user.Username); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unlock user"})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func loadUserParam(param string) (*models.User, error) {
+	id, err := strconv.ParseUint(param, 10, 64)
+	if err != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	var user models.User
+	if err := global.Db.First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+type updateUserRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+type suspendUserRequest struct {
+	Reason string `json:"reason"`
+}
+
+// AdminUserDTO extends UserDTO with fields only administrators may see.
+type AdminUserDTO struct {
+	UserDTO
+	SuspendedAt     *time.Time `json:"suspendedAt,omitempty"`
+	SuspendedReason string     `json:"suspendedReason,omitempty"`
+	PostCount       int64      `json:"postCount"`
+}
+
+// ListUsers supports q (username, display name or email), role and
+// status=active|suspended filters.
+func ListUsers(ctx *gin.Context) {
+	page, pageSize := utils.GetPagination(ctx)
+
+	query := global.Db.Model(&models.User{})
+	if q := strings.TrimSpace(ctx.Query("q")); q != "" {
+		like := "%" + q + "%"
+		query = query.Where("username LIKE ? OR display_name LIKE ? OR email LIKE ?", like, like, like)
+	}
+	if role := strings.TrimSpace(ctx.Query("role")); role != "" {
+		if !models.IsValidRole(role) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
+			return
+		}
+		query = query.Where("role = ?", role)
+	}
+	switch ctx.Query("status") {
+	case "":
+	case "active":
+		query = query.Where("suspended_at IS NULL")
+	case "suspended":
+		query = query.Where("suspended_at IS NOT NULL")
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "status must be active or suspended"})
+		return
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count users"})
+		return
+	}
+
+	var users []models.User
+	if err := query.
+		Order("created_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&users).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load users"})
+		return
+	}
+
+	postCounts, err := countPostsByAuthor(users)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count posts"})
+		return
+	}
+
+	response := make([]AdminUserDTO, 0, len(users))
+	for _, user := range users {
+		response = append(response, buildAdminUserDTO(user, postCounts[user.ID]))
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":     response,
+		"page":     page,
+		"pageSize": pageSize,
+		"total":    total,
+	})
+}
+
+func GetUser(ctx *gin.Context) {
+	user, err := loadUserParam(ctx.Param("id"))
+	if err != nil {
+		handleUserLoadError(ctx, err)
+		return
+	}
+
+	respondAdminUser(ctx, *user)
+}
+
+func UpdateUserRole(ctx *gin.Context) {
+	user, err := loadUserParam(ctx.Param("id"))
+	if err != nil {
+		handleUserLoadError(ctx, err)
+		return
+	}
+
+	var input updateUserRoleRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role := strings.ToLower(strings.TrimSpace(input.Role))
+	if !models.IsValidRole(role) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
+		return
+	}
+	if isCurrentUser(ctx, user.ID) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "you cannot change your own role"})
+		return
+	}
+
+	if err := global.Db.Model(user).Update("role", role).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update role"})
+		return
+	}
+
+	respondAdminUser(ctx, *user)
+}
+
+// SuspendUser blocks sign-in and every existing session and access token
+// until the account is unsuspended.
+func SuspendUser(ctx *gin.Context) {
+	user, err := loadUserParam(ctx.Param("id"))
+	if err != nil {
+		handleUserLoadError(ctx, err)
+		return
+	}
+
+	var input suspendUserRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reason := strings.TrimSpace(input.Reason)
+	if len(reason) > 255 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "reason must be at most 255 characters"})
+		return
+	}
+	if isCurrentUser(ctx, user.ID) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "you cannot suspend yourself"})
+		return
+	}
+
+	if err := global.Db.Model(user).Updates(map[string]interface{}{
+		"suspended_at":     time.Now(),
+		"suspended_reason": reason,
+	}).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to suspend user"})
+		return
+	}
+
+	if err := revokeUserSessions(user.ID, 0); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke sessions"})
+		return
+	}
+
+	respondAdminUser(ctx, *user)
+}
+
+func UnsuspendUser(ctx *gin.Context) {
+	user, err := loadUserParam(ctx.Param("id"))
+	if err != nil {
+		handleUserLoadError(ctx, err)
+		return
+	}
+
+	if err := global.Db.Model(user).Updates(map[string]interface{}{
+		"suspended_at":     nil,
+		"suspended_reason": "",
+	}).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unsuspend user"})
+		return
+	}
+
+	respondAdminUser(ctx, *user)
+}
+
+// DeleteUser requires posts=transfer&transferTo=<id> or posts=archive so an
+// account is never removed with its posts left in an unclear state.
+func DeleteUser(ctx *gin.Context) {
+	user, err := loadUserParam(ctx.Param("id"))
+	if err != nil {
+		handleUserLoadError(ctx, err)
+		return
+	}
+
+	if isCurrentUser(ctx, user.ID) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "you cannot delete yourself"})
+		return
+	}
+
+	var transferTo *models.User
+	switch ctx.Query("posts") {
+	case "transfer":
+		transferTo, err = loadUserParam(ctx.Query("transferTo"))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "transferTo must be an existing user"})
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load user"})
+			return
+		}
+		if transferTo.ID == user.ID || transferTo.IsSuspended() ||
+			!transferTo.HasPermission(models.PermissionWritePosts) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "transferTo must be another active user who can write posts"})
+			return
+		}
+	case "archive":
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "posts must be transfer or archive"})
+		return
+	}
+
+	adminID, _ := currentUserID(ctx)
+	err = global.Db.Transaction(func(tx *gorm.DB) error {
+		// Trashed posts are included so a later restore never brings back a
+		// post owned by the deleted account.
+		posts := tx.Unscoped().Model(&models.Post{}).Where("author_id = ?", user.ID)
+		if transferTo != nil {
+			if err := posts.Update("author_id", transferTo.ID).Error; err != nil {
+				return err
+			}
+		} else if err := archiveUserPosts(tx, user.ID, adminID); err != nil {
+			return err
+		}
+
+		for _, model := range []interface{}{
+			&models.PersonalAccessToken{},
+			&models.RecoveryCode{},
+			&models.UserIdentity{},
+			&models.UserToken{},
+		} {
+			if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", user.ID).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		// Release the email so it can be registered again; the username stays
+		// reserved so nobody can take over the departed author's name.
+		if err := tx.Model(user).Update("email", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(user).Error
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete user"})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func respondAdminUser(ctx *gin.Context, user models.User) {
+	postCounts, err := countPostsByAuthor([]models.User{user})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count posts"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": buildAdminUserDTO(user, postCounts[user.ID])})
+}
+
+func countPostsByAuthor(users []models.User) (map[uint]int64, error) {
+	counts := make(map[uint]int64, len(users))
+	if len(users) == 0 {
+		return counts, nil
+	}
+
+	ids := make([]uint, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+
+	var rows []struct {
+		AuthorID uint
+		Total    int64
+	}
+	if err := global.Db.Model(&models.Post{}).
+		Select("author_id, COUNT(*) AS total").
+		Where("author_id IN ?", ids).
+		Group("author_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		counts[row.AuthorID] = row.Total
+	}
+	return counts, nil
+}
+
+func buildAdminUserDTO(user models.User, postCount int64) AdminUserDTO {
+	return AdminUserDTO{
+		UserDTO:         buildUserDTO(user),
+		SuspendedAt:     user.SuspendedAt,
+		SuspendedReason: user.SuspendedReason,
+		PostCount:       postCount,
+	}
+}
+
+func isCurrentUser(ctx *gin.Context, id uint) bool {
+	currentID, ok := currentUserID(ctx)
+	return ok && currentID == id
+}
+
+// archiveUserPosts archives every post by userID, trashed ones included,
+// noting the change in each post's status history.
+func archiveUserPosts(tx *gorm.DB, userID, adminID uint) error {
+	var posts []models.Post
+	if err := tx.Unscoped().Model(&models.Post{}).Select("id", "status").
+		Where("author_id = ? AND status <> ?", userID, models.PostStatusArchived).
+		Find(&posts).Error; err != nil {
+		return err
+	}
+
+	for _, post := range posts {
+		previous := post.Status
+		if err := tx.Unscoped().Model(&post).Update("status", models.PostStatusArchived).Error; err != nil {
+			return err
+		}
+		if err := models.RecordTransition(tx, post.ID, &adminID, previous, models.PostStatusArchived, "author account deleted"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"gogogo/global"
+	"gogogo/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setupDeleteUserTest creates an admin, an author with one live and one
+// trashed post, and a router deleting users as the admin.
+func setupDeleteUserTest(t *testing.T) (*gin.Engine, models.User, []models.Post) {
+	t.Helper()
+	setupTestDB(t, allTestModels...)
+
+	admin := createTestUser(t, "admin", models.RoleAdmin)
+	author := createTestUser(t, "author", models.RoleAuthor)
+	posts := []models.Post{
+		{Title: "Live", Slug: "live", Status: models.PostStatusPublished, AuthorID: author.ID},
+		{Title: "Trashed", Slug: "trashed", Status: models.PostStatusDraft, AuthorID: author.ID},
+	}
+	if err := global.Db.Create(&posts).Error; err != nil {
+		t.Fatalf("create posts: %v", err)
+	}
+	if err := global.Db.Delete(&posts[1]).Error; err != nil {
+		t.Fatalf("trash post: %v", err)
+	}
+
+	router := gin.New()
+	asUser(router, admin)
+	router.DELETE("/api/admin/users/:id", DeleteUser)
+	return router, author, posts
+}
+
+func loadPostUnscoped(t *testing.T, id uint) models.Post {
+	t.Helper()
+	var post models.Post
+	if err := global.Db.Unscoped().First(&post, id).Error; err != nil {
+		t.Fatalf("load post %d: %v", id, err)
+	}
+	return post
+}
+
+func TestDeleteUserTransfersTrashedPosts(t *testing.T) {
+	router, author, posts := setupDeleteUserTest(t)
+	heir := createTestUser(t, "heir", models.RoleAuthor)
+
+	path := "/api/admin/users/" + strconv.FormatUint(uint64(author.ID), 10) +
+		"?posts=transfer&transferTo=" + strconv.FormatUint(uint64(heir.ID), 10)
+	if status, body := serveJSON(t, router, http.MethodDelete, path, nil); status != http.StatusNoContent {
+		t.Fatalf("delete: status %d, body %v", status, body)
+	}
+
+	for _, post := range posts {
+		if got := loadPostUnscoped(t, post.ID).AuthorID; got != heir.ID {
+			t.Fatalf("post %q owned by %d, want %d", post.Title, got, heir.ID)
+		}
+	}
+}
+
+func TestDeleteUserArchivesTrashedPosts(t *testing.T) {
+	router, author, posts := setupDeleteUserTest(t)
+
+	path := "/api/admin/users/" + strconv.FormatUint(uint64(author.ID), 10) + "?posts=archive"
+	if status, body := serveJSON(t, router, http.MethodDelete, path, nil); status != http.StatusNoContent {
+		t.Fatalf("delete: status %d, body %v", status, body)
+	}
+
+	for _, post := range posts {
+		if got := loadPostUnscoped(t, post.ID).Status; got != models.PostStatusArchived {
+			t.Fatalf("post %q has status %s, want archived", post.Title, got)
+		}
+	}
+	trashed := loadPostUnscoped(t, posts[1].ID)
+	if !trashed.DeletedAt.Valid {
+		t.Fatal("archiving restored the trashed post")
+	}
+
+	var transitions int64
+	global.Db.Model(&models.PostTransition{}).Where("to_status = ?", models.PostStatusArchived).Count(&transitions)
+	if transitions != 2 {
+		t.Fatalf("%d archive transitions recorded, want 2", transitions)
+	}
+}
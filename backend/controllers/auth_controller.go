@@ -2,9 +2,11 @@ package controllers
 
 import (
 	"errors"
+	"log"
 	"net/http"
 	"strings"
 
+	"gogogo/config"
 	"gogogo/global"
 	"gogogo/models"
 	"gogogo/utils"
@@ -50,6 +52,10 @@ func Register(ctx *gin.Context) {
 	}
 
 	if input.Email != "" {
+		if !isValidEmail(input.Email) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid email address"})
+			return
+		}
 		if err := global.Db.Where("email = ?", input.Email).First(&existing).Error; err == nil {
 			ctx.JSON(http.StatusConflict, gin.H{"error": "email already registered"})
 			return
@@ -76,6 +82,7 @@ func Register(ctx *gin.Context) {
 		Email:       emailPtr,
 		DisplayName: input.DisplayName,
 		Password:    hashedPwd,
+		Role:        defaultUserRole(),
 	}
 
 	if strings.TrimSpace(user.DisplayName) == "" {
@@ -87,16 +94,17 @@ func Register(ctx *gin.Context) {
 		return
 	}
 
-	token, err := utils.GenerateJWT(user.ID, user.Username)
+	if err := sendVerificationEmail(user); err != nil {
+		log.Printf("failed to issue verification email for user %d: %v", user.ID, err)
+	}
+
+	tokens, err := issueSession(ctx, user)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, gin.H{
-		"token": token,
-		"user":  buildUserDTO(user),
-	})
+	ctx.JSON(http.StatusCreated, authResponse(user, tokens))
 }
 
 func Login(ctx *gin.Context) {
@@ -106,10 +114,21 @@ func Login(ctx *gin.Context) {
 		return
 	}
 
+	throttleKeys := loginThrottleKeys(ctx, input.Username)
+	block, err := checkLoginAllowed(throttleKeys)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check login attempts"})
+		return
+	}
+	if block != nil {
+		respondLoginBlocked(ctx, block)
+		return
+	}
+
 	var user models.User
 	if err := global.Db.Where("username = ?", input.Username).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+			rejectLogin(ctx, throttleKeys)
 			return
 		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch user"})
@@ -117,18 +136,48 @@ func Login(ctx *gin.Context) {
 	}
 
 	if !utils.CheckPassword(input.Password, user.Password) {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		rejectLogin(ctx, throttleKeys)
+		return
+	}
+
+	if user.IsSuspended() {
+		respondUserSuspended(ctx)
 		return
 	}
 
-	token, err := utils.GenerateJWT(user.ID, user.Username)
+	// Failures are only cleared once the second factor succeeds, otherwise a
+	// known password would reset the counter guarding the TOTP step.
+	if user.MFAEnabled() {
+		respondMFAChallenge(ctx, user)
+		return
+	}
+
+	if err := clearLoginFailures(user.Username); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reset login attempts"})
+		return
+	}
+
+	tokens, err := issueSession(ctx, user)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"token": token,
-		"user":  buildUserDTO(user),
-	})
+	ctx.JSON(http.StatusOK, authResponse(user, tokens))
+}
+
+func rejectLogin(ctx *gin.Context, throttleKeys []string) {
+	if err := recordLoginFailure(throttleKeys); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record login attempt"})
+		return
+	}
+	ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+}
+
+func defaultUserRole() string {
+	role := strings.ToLower(strings.TrimSpace(config.AppConfig.Auth.DefaultRole))
+	if !models.IsValidRole(role) || role == models.RoleAdmin {
+		return models.RoleAuthor
+	}
+	return role
 }
@@ -89,6 +89,8 @@ func UpdateCategory(ctx *gin.Context) {
 		return
 	}
 
+	previousSlug := category.Slug
+
 	var input updateCategoryRequest
 	if err := ctx.ShouldBindJSON(&input); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -119,7 +121,13 @@ func UpdateCategory(ctx *gin.Context) {
 		category.Description = *input.Description
 	}
 
-	if err := global.Db.Save(&category).Error; err != nil {
+	err = global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&category).Error; err != nil {
+			return err
+		}
+		return models.RecordSlugChange(tx, models.SlugEntityCategory, category.ID, previousSlug, category.Slug)
+	})
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update category"})
 		return
 	}
@@ -1,23 +1,59 @@
 package controllers
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"gogogo/config"
 	"gogogo/global"
 	"gogogo/models"
+	"gogogo/spam"
+	"gogogo/utils"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+var postSummaryColumns = []string{"id", "author_id", "status", "published_at", "visibility", "password"}
+
+const commentViewTree = "tree"
+
+const (
+	commentSortOldest = "oldest"
+	commentSortNewest = "newest"
+	commentSortTop    = "top"
+)
+
+// commentCursor marks the last thread of a page. Replies is only used when
+// sorting by top.
+type commentCursor struct {
+	ID      uint `json:"id"`
+	Replies int  `json:"r,omitempty"`
+}
+
 type commentRequest struct {
 	AuthorName string `json:"authorName"`
 	Body       string `json:"body" binding:"required"`
+	ParentID   *uint  `json:"parentId"`
+}
+
+type updateCommentRequest struct {
+	Body string `json:"body" binding:"required"`
 }
 
+// ListComments returns a page of a post's comment threads. Threads are
+// top-level comments ordered by ?sort= (oldest, the default, newest, or top
+// for the most direct replies); each comes with its first replies, oldest
+// first at every level, and a repliesCursor when the thread has more. Pages
+// are fetched with ?cursor= set to the previous page's nextCursor. With
+// ?view=tree the replies are nested under their parents instead of following
+// them.
 func ListComments(ctx *gin.Context) {
 	post, err := loadPostSummary(ctx.Param("id"))
 	if err != nil {
@@ -28,19 +64,155 @@ func ListComments(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load post"})
 		return
 	}
+	if !checkCommentAccess(ctx, post) {
+		return
+	}
+
+	sortBy := ctx.DefaultQuery("sort", commentSortOldest)
+	switch sortBy {
+	case commentSortOldest, commentSortNewest, commentSortTop:
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "sort must be oldest, newest or top"})
+		return
+	}
+
+	var cursor *commentCursor
+	if raw := ctx.Query("cursor"); raw != "" {
+		decoded, err := decodeCommentCursor(raw)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		cursor = &decoded
+	}
+
+	statuses := visibleCommentStatuses(ctx, post)
+	visible := visibleComments(post.ID, statuses)
+
+	var total int64
+	if err := global.Db.Model(&models.Comment{}).
+		Where("post_id = ? AND status IN ?", post.ID, statuses).
+		Count(&total).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count comments"})
+		return
+	}
 
-	query := global.Db.Where("post_id = ?", post.ID).Order("created_at ASC").Preload("User")
-	if userID, ok := optionalUserID(ctx); !ok || userID != post.AuthorID {
-		query = query.Where("approved = ?", true)
+	_, pageSize := utils.GetPagination(ctx)
+	threads := global.Db.Scopes(visible).Where("parent_id IS NULL")
+	switch sortBy {
+	case commentSortNewest:
+		if cursor != nil {
+			threads = threads.Where("id < ?", cursor.ID)
+		}
+		threads = threads.Order("id DESC")
+	case commentSortTop:
+		if cursor != nil {
+			threads = threads.Where("reply_count < ? OR (reply_count = ? AND id > ?)", cursor.Replies, cursor.Replies, cursor.ID)
+		}
+		threads = threads.Order("reply_count DESC, id ASC")
+	default:
+		if cursor != nil {
+			threads = threads.Where("id > ?", cursor.ID)
+		}
+		threads = threads.Order("id ASC")
 	}
 
-	var comments []models.Comment
-	if err := query.Find(&comments).Error; err != nil {
+	// One extra row tells whether there is another page.
+	var roots []models.Comment
+	if err := threads.Preload("User").Limit(pageSize + 1).Find(&roots).Error; err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load comments"})
 		return
 	}
+	var nextCursor *string
+	if len(roots) > pageSize {
+		roots = roots[:pageSize]
+		last := roots[len(roots)-1]
+		next := encodeCommentCursor(commentCursor{ID: last.ID, Replies: last.ReplyCount})
+		nextCursor = &next
+	}
 
-	ctx.JSON(http.StatusOK, gin.H{"data": buildCommentDTOs(comments)})
+	comments := make([]models.Comment, 0, len(roots))
+	comments = append(comments, roots...)
+	replyCursors := make(map[uint]string)
+	for _, root := range roots {
+		replies, next, err := loadThreadReplies(visible, root.ID, 0)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load comments"})
+			return
+		}
+		comments = append(comments, replies...)
+		if next != nil {
+			replyCursors[root.ID] = *next
+		}
+	}
+
+	dtos := buildCommentDTOs(threadComments(comments))
+	for i := range dtos {
+		if next, ok := replyCursors[dtos[i].ID]; ok {
+			dtos[i].RepliesCursor = &next
+		}
+	}
+	var data interface{} = dtos
+	if ctx.Query("view") == commentViewTree {
+		data = buildCommentTree(dtos)
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":       data,
+		"nextCursor": nextCursor,
+		"total":      total,
+	})
+}
+
+// ListCommentReplies returns the next replies of a thread after ?cursor=,
+// taken from a thread's repliesCursor or the previous nextCursor. Replies are
+// oldest first, so every reply comes after its parent.
+func ListCommentReplies(ctx *gin.Context) {
+	post, err := loadPostSummary(ctx.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load post"})
+		return
+	}
+	if !checkCommentAccess(ctx, post) {
+		return
+	}
+
+	var after uint
+	if raw := ctx.Query("cursor"); raw != "" {
+		cursor, err := decodeCommentCursor(raw)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		after = cursor.ID
+	}
+
+	visible := visibleComments(post.ID, visibleCommentStatuses(ctx, post))
+	var root models.Comment
+	if err := global.Db.Scopes(visible).
+		Where("id = ? AND parent_id IS NULL", ctx.Param("commentId")).
+		First(&root).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "comment not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load comment"})
+		return
+	}
+
+	replies, next, err := loadThreadReplies(visible, root.ID, after)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load comments"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":       buildCommentDTOs(replies),
+		"nextCursor": next,
+	})
 }
 
 func CreateComment(ctx *gin.Context) {
@@ -53,6 +225,9 @@ func CreateComment(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load post"})
 		return
 	}
+	if !checkCommentAccess(ctx, post) {
+		return
+	}
 
 	var input commentRequest
 	if err := ctx.ShouldBindJSON(&input); err != nil {
@@ -70,6 +245,20 @@ func CreateComment(ctx *gin.Context) {
 	comment.PostID = post.ID
 	comment.Body = input.Body
 
+	if input.ParentID != nil {
+		parent, parentErr := loadReplyParent(post.ID, *input.ParentID)
+		if parentErr != nil {
+			respondReplyParentError(ctx, parentErr)
+			return
+		}
+		comment.ParentID = &parent.ID
+		comment.Depth = parent.Depth + 1
+		comment.RootID = parent.RootID
+		if comment.RootID == nil {
+			comment.RootID = &parent.ID
+		}
+	}
+
 	if userID, ok := optionalUserID(ctx); ok {
 		user, userErr := loadUserByID(ctx, userID)
 		if userErr != nil {
@@ -85,20 +274,43 @@ func CreateComment(ctx *gin.Context) {
 		if comment.AuthorName == "" {
 			comment.AuthorName = user.Username
 		}
-		comment.Approved = true
 	} else {
 		if input.AuthorName == "" {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": "author name is required for guest comments"})
 			return
 		}
 		comment.AuthorName = input.AuthorName
-		comment.Approved = true
 	}
+	comment.Status = initialCommentStatus(ctx, post, comment)
+	comment.IPAddress = ctx.ClientIP()
+	comment.UserAgent = truncate(ctx.Request.UserAgent(), 255)
+	comment.BodyHash = models.HashCommentBody(comment.Body)
 
-	if err := global.Db.Create(&comment).Error; err != nil {
+	verdict, err := checkCommentSpam(ctx, post, &comment)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check comment"})
+		return
+	}
+
+	err = global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
+		}
+		if comment.ParentID == nil || !comment.IsApproved() {
+			return nil
+		}
+		return models.RecountReplies(tx, []uint{*comment.ParentID})
+	})
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create comment"})
 		return
 	}
+	// Rejected comments are kept for moderators to review and train on, but
+	// the sender only learns that it was refused.
+	if verdict == spam.Reject {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "comment was rejected as spam"})
+		return
+	}
 
 	if err := global.Db.Preload("User").First(&comment, comment.ID).Error; err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load comment"})
@@ -109,6 +321,384 @@ func CreateComment(ctx *gin.Context) {
 	ctx.JSON(http.StatusCreated, gin.H{"data": dto[0]})
 }
 
+// UpdateComment lets a signed-in commenter change the body of their own
+// comment within comments.edit_window_minutes. The edit goes through the
+// moderation policy and spam filter again, so an approved comment can be sent
+// back to the queue; it is never approved by editing.
+func UpdateComment(ctx *gin.Context) {
+	comment, post, ok := loadCommentParam(ctx)
+	if !ok {
+		return
+	}
+	if !checkCommentOwner(ctx, post, comment) {
+		return
+	}
+
+	var input updateCommentRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.TrimSpace(input.Body) == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "comment body is required"})
+		return
+	}
+
+	if input.Body != comment.Body {
+		wasApproved := comment.IsApproved()
+		now := time.Now()
+		comment.PreviousBody = comment.Body
+		comment.Body = input.Body
+		comment.BodyHash = models.HashCommentBody(comment.Body)
+		comment.EditedAt = &now
+		if wasApproved && initialCommentStatus(ctx, post, comment) != models.CommentStatusApproved {
+			comment.Status = models.CommentStatusPending
+		}
+
+		verdict, err := checkCommentSpam(ctx, post, &comment)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check comment"})
+			return
+		}
+		// Unlike a new comment, a refused edit is not stored: the comment
+		// keeps its previous body.
+		if verdict == spam.Reject {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "comment was rejected as spam"})
+			return
+		}
+
+		err = global.Db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&comment).Select(
+				"body", "body_hash", "previous_body", "edited_at", "status", "spam_score", "spam_reasons",
+			).Updates(&comment).Error; err != nil {
+				return err
+			}
+			if comment.ParentID == nil || comment.IsApproved() == wasApproved {
+				return nil
+			}
+			return models.RecountReplies(tx, []uint{*comment.ParentID})
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update comment"})
+			return
+		}
+	}
+
+	if err := global.Db.Preload("User").First(&comment, comment.ID).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load comment"})
+		return
+	}
+
+	dto := buildCommentDTOs([]models.Comment{comment})
+	ctx.JSON(http.StatusOK, gin.H{"data": dto[0]})
+}
+
+// DeleteComment removes a comment. Its author may delete it within the edit
+// window, and the post's author and comment moderators at any time. A deleted
+// comment with replies stays in the thread as a tombstone.
+func DeleteComment(ctx *gin.Context) {
+	comment, post, ok := loadCommentParam(ctx)
+	if !ok {
+		return
+	}
+	if !canModerateComments(ctx, post.AuthorID) && !checkCommentOwner(ctx, post, comment) {
+		return
+	}
+
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&comment).Error; err != nil {
+			return err
+		}
+		if comment.ParentID == nil || !comment.IsApproved() {
+			return nil
+		}
+		return models.RecountReplies(tx, []uint{*comment.ParentID})
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete comment"})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// loadCommentParam loads the comment in :id and the summary of its post. The
+// comment is reported missing when the post is hidden from the current user.
+func loadCommentParam(ctx *gin.Context) (models.Comment, models.Post, bool) {
+	var comment models.Comment
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid comment id"})
+		return comment, models.Post{}, false
+	}
+	if err := global.Db.First(&comment, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "comment not found"})
+			return comment, models.Post{}, false
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load comment"})
+		return comment, models.Post{}, false
+	}
+
+	post, err := loadPostSummary(strconv.FormatUint(uint64(comment.PostID), 10))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "comment not found"})
+			return comment, post, false
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load post"})
+		return comment, post, false
+	}
+	if !checkCommentAccess(ctx, post) {
+		return comment, post, false
+	}
+	return comment, post, true
+}
+
+// checkCommentOwner allows the comment's signed-in author through while the
+// edit window is open. The window does not apply to the post's author or to
+// comment moderators.
+func checkCommentOwner(ctx *gin.Context, post models.Post, comment models.Comment) bool {
+	userID, ok := currentUserID(ctx)
+	if !ok || comment.UserID == nil || *comment.UserID != userID {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "you can only change your own comments"})
+		return false
+	}
+	if canModerateComments(ctx, post.AuthorID) {
+		return true
+	}
+	if window := config.AppConfig.Comments.EditWindowMinutes; window > 0 &&
+		time.Since(comment.CreatedAt) > time.Duration(window)*time.Minute {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "the time to change this comment has passed"})
+		return false
+	}
+	return true
+}
+
+// visibleCommentStatuses lists the statuses the current user may see on a
+// post. Moderators also see comments waiting for them, marked as not
+// approved.
+func visibleCommentStatuses(ctx *gin.Context, post models.Post) []string {
+	statuses := []string{models.CommentStatusApproved}
+	if canModerateComments(ctx, post.AuthorID) {
+		statuses = append(statuses, models.CommentStatusPending)
+	}
+	return statuses
+}
+
+// visibleComments limits a query to a post's comments in statuses, plus
+// deleted ones that are kept as tombstones.
+func visibleComments(postID uint, statuses []string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Scopes(models.WithTombstones(statuses)).Where("post_id = ? AND status IN ?", postID, statuses)
+	}
+}
+
+// loadThreadReplies loads up to comments.replies_per_thread replies of a
+// thread with ids above after, oldest first. A reply is always newer than
+// its parent, so each batch continues the thread without gaps. next is nil
+// once the thread is exhausted.
+func loadThreadReplies(visible func(db *gorm.DB) *gorm.DB, rootID uint, after uint) ([]models.Comment, *string, error) {
+	limit := config.AppConfig.Comments.RepliesPerThread
+	if limit <= 0 {
+		limit = 20
+	}
+
+	var replies []models.Comment
+	if err := global.Db.Scopes(visible).
+		Where("root_id = ? AND id > ?", rootID, after).
+		Preload("User").
+		Order("id ASC").
+		Limit(limit + 1).
+		Find(&replies).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(replies) <= limit {
+		return replies, nil, nil
+	}
+
+	replies = replies[:limit]
+	next := encodeCommentCursor(commentCursor{ID: replies[len(replies)-1].ID})
+	return replies, &next, nil
+}
+
+func encodeCommentCursor(cursor commentCursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCommentCursor(value string) (commentCursor, error) {
+	var cursor commentCursor
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, err
+	}
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return cursor, err
+	}
+	if cursor.ID == 0 {
+		return cursor, errors.New("cursor has no id")
+	}
+	return cursor, nil
+}
+
+// approvedCommentCounts counts the approved comments of each post, replies
+// included, with one grouped query.
+func approvedCommentCounts(postIDs []uint) (map[uint]int64, error) {
+	counts := make(map[uint]int64, len(postIDs))
+	if len(postIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		PostID uint
+		Total  int64
+	}
+	if err := global.Db.Model(&models.Comment{}).
+		Select("post_id, COUNT(*) AS total").
+		Where("post_id IN ? AND status = ?", postIDs, models.CommentStatusApproved).
+		Group("post_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.PostID] = row.Total
+	}
+	return counts, nil
+}
+
+// respondCommentCounts fills in CommentCount, responding with an error if the
+// counts could not be loaded.
+func respondCommentCounts(ctx *gin.Context, posts []*PostDTO) bool {
+	if err := withCommentCounts(posts); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count comments"})
+		return false
+	}
+	return true
+}
+
+func postDTORefs(posts []PostDTO) []*PostDTO {
+	refs := make([]*PostDTO, 0, len(posts))
+	for i := range posts {
+		refs = append(refs, &posts[i])
+	}
+	return refs
+}
+
+// withCommentCounts sets CommentCount on the given posts. Locked posts are
+// skipped, as their comments are hidden until unlocked.
+func withCommentCounts(posts []*PostDTO) error {
+	ids := make([]uint, 0, len(posts))
+	for _, post := range posts {
+		if !post.Locked {
+			ids = append(ids, post.ID)
+		}
+	}
+	counts, err := approvedCommentCounts(ids)
+	if err != nil {
+		return err
+	}
+	for _, post := range posts {
+		if post.Locked {
+			continue
+		}
+		count := counts[post.ID]
+		post.CommentCount = &count
+	}
+	return nil
+}
+
+var (
+	errReplyParentNotFound = errors.New("parent comment not found")
+	errReplyParentPending  = errors.New("cannot reply to a comment that is not approved")
+	errReplyTooDeep        = errors.New("reply thread is too deep")
+)
+
+// loadReplyParent loads the comment being replied to and checks it belongs to
+// the same post, is approved and leaves room for another level of replies.
+func loadReplyParent(postID, parentID uint) (models.Comment, error) {
+	var parent models.Comment
+	if err := global.Db.Where("post_id = ?", postID).First(&parent, parentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return parent, errReplyParentNotFound
+		}
+		return parent, err
+	}
+	if !parent.IsApproved() {
+		return parent, errReplyParentPending
+	}
+	if maxDepth := config.AppConfig.Comments.MaxDepth; maxDepth > 0 && parent.Depth+1 > maxDepth {
+		return parent, errReplyTooDeep
+	}
+	return parent, nil
+}
+
+func respondReplyParentError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errReplyParentNotFound),
+		errors.Is(err, errReplyParentPending),
+		errors.Is(err, errReplyTooDeep):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load parent comment"})
+	}
+}
+
+// threadComments orders comments so every reply directly follows its parent's
+// subtree, replies oldest first. Top-level comments keep the order they were
+// given in; replies whose parent is not in the list (removed or hidden from
+// this reader) are shown at the top level after them.
+func threadComments(comments []models.Comment) []models.Comment {
+	present := make(map[uint]bool, len(comments))
+	for _, comment := range comments {
+		present[comment.ID] = true
+	}
+
+	children := make(map[uint][]models.Comment)
+	var roots []models.Comment
+	for _, comment := range comments {
+		if comment.ParentID != nil && present[*comment.ParentID] {
+			children[*comment.ParentID] = append(children[*comment.ParentID], comment)
+			continue
+		}
+		roots = append(roots, comment)
+	}
+
+	byAge := func(list []models.Comment) {
+		sort.SliceStable(list, func(i, j int) bool {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		})
+	}
+
+	result := make([]models.Comment, 0, len(comments))
+	var walk func(list []models.Comment)
+	walk = func(list []models.Comment) {
+		for _, comment := range list {
+			result = append(result, comment)
+			replies := children[comment.ID]
+			byAge(replies)
+			walk(replies)
+		}
+	}
+	walk(roots)
+	return result
+}
+
+// checkCommentAccess applies the post's visibility to its comments: hidden
+// posts have none, and locked ones need unlocking first.
+func checkCommentAccess(ctx *gin.Context, post models.Post) bool {
+	switch resolvePostAccess(ctx, post) {
+	case postHidden:
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return false
+	case postLocked:
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "post is password protected"})
+		return false
+	}
+	return true
+}
+
+// loadPostSummary loads just the columns needed to check access to a post.
 func loadPostSummary(param string) (models.Post, error) {
 	param = strings.TrimSpace(param)
 	var post models.Post
@@ -117,10 +707,10 @@ func loadPostSummary(param string) (models.Post, error) {
 	}
 
 	if id, err := strconv.ParseUint(param, 10, 64); err == nil {
-		err = global.Db.Select("id", "author_id").First(&post, id).Error
+		err = global.Db.Select(postSummaryColumns).First(&post, id).Error
 		return post, err
 	}
 
-	err := global.Db.Select("id", "author_id").Where("slug = ?", param).First(&post).Error
+	err := global.Db.Select(postSummaryColumns).Where("slug = ?", param).First(&post).Error
 	return post, err
 }
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"gogogo/global"
+	"gogogo/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func createTestComment(t *testing.T, post models.Post, parent *models.Comment, status string) models.Comment {
+	t.Helper()
+
+	comment := models.Comment{PostID: post.ID, AuthorName: "guest", Body: "hello", Status: status}
+	if parent != nil {
+		comment.ParentID = &parent.ID
+		comment.RootID = &parent.ID
+		if parent.RootID != nil {
+			comment.RootID = parent.RootID
+		}
+		comment.Depth = parent.Depth + 1
+	}
+	if err := global.Db.Create(&comment).Error; err != nil {
+		t.Fatalf("create comment: %v", err)
+	}
+	return comment
+}
+
+func listCommentThreads(t *testing.T, router *gin.Engine, post models.Post) []interface{} {
+	t.Helper()
+	status, body := serveJSON(t, router, http.MethodGet, "/api/posts/"+strconv.FormatUint(uint64(post.ID), 10)+"/comments?view=tree", nil)
+	if status != http.StatusOK {
+		t.Fatalf("list comments: status %d, body %v", status, body)
+	}
+	threads, _ := body["data"].([]interface{})
+	return threads
+}
+
+func TestDeletedCommentStaysForItsPendingReplies(t *testing.T) {
+	setupTestDB(t, allTestModels...)
+	author := createTestUser(t, "alice", models.RoleAuthor)
+	post := createTestPost(t, author, "Hello", models.PostStatusPublished)
+
+	parent := createTestComment(t, post, nil, models.CommentStatusApproved)
+	createTestComment(t, post, &parent, models.CommentStatusPending)
+	if err := global.Db.Delete(&parent).Error; err != nil {
+		t.Fatalf("delete comment: %v", err)
+	}
+
+	// The post's author moderates its comments and sees the pending reply in
+	// its thread, under the deleted parent.
+	moderator := gin.New()
+	asUser(moderator, author)
+	moderator.GET("/api/posts/:id/comments", ListComments)
+	threads := listCommentThreads(t, moderator, post)
+	if len(threads) != 1 {
+		t.Fatalf("moderator sees %d threads, want the tombstone", len(threads))
+	}
+	thread := threads[0].(map[string]interface{})
+	replies, _ := thread["replies"].([]interface{})
+	if thread["deleted"] != true || len(replies) != 1 {
+		t.Fatalf("thread %v, want a tombstone with one reply", thread)
+	}
+
+	// Readers cannot see the reply, so there is nothing to keep the tombstone for.
+	reader := gin.New()
+	reader.GET("/api/posts/:id/comments", ListComments)
+	if threads := listCommentThreads(t, reader, post); len(threads) != 0 {
+		t.Fatalf("reader sees %v, want no threads", threads)
+	}
+}
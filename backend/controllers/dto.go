@@ -3,17 +3,29 @@ package controllers
 import (
 	"time"
 
+	"gogogo/markdown"
 	"gogogo/models"
 )
 
 type UserDTO struct {
-	ID          uint      `json:"id"`
-	Username    string    `json:"username"`
-	Email       string    `json:"email,omitempty"`
-	DisplayName string    `json:"displayName"`
-	Bio         string    `json:"bio,omitempty"`
-	AvatarURL   string    `json:"avatarUrl,omitempty"`
-	CreatedAt   time.Time `json:"createdAt"`
+	ID              uint       `json:"id"`
+	Username        string     `json:"username"`
+	Email           string     `json:"email,omitempty"`
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt,omitempty"`
+	Role            string     `json:"role,omitempty"`
+	MFAEnabled      bool       `json:"mfaEnabled,omitempty"`
+	DisplayName     string     `json:"displayName"`
+	Bio             string     `json:"bio,omitempty"`
+	AvatarURL       string     `json:"avatarUrl,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt"`
+}
+
+type AuthorProfileDTO struct {
+	UserDTO
+	PostCount         int64      `json:"postCount"`
+	CommentCount      int64      `json:"commentCount"`
+	FirstPublishedAt  *time.Time `json:"firstPublishedAt,omitempty"`
+	LatestPublishedAt *time.Time `json:"latestPublishedAt,omitempty"`
 }
 
 type CategoryDTO struct {
@@ -31,30 +43,52 @@ type TagDTO struct {
 	CreatedAt time.Time `json:"createdAt"`
 }
 
+// CommentDTO is a comment as readers see it. Deleted comments that are kept
+// for their replies come back with Deleted set and no author or body.
 type CommentDTO struct {
-	ID         uint      `json:"id"`
-	AuthorName string    `json:"authorName"`
-	Body       string    `json:"body"`
-	Approved   bool      `json:"approved"`
-	CreatedAt  time.Time `json:"createdAt"`
-	User       *UserDTO  `json:"user,omitempty"`
+	ID         uint       `json:"id"`
+	AuthorName string     `json:"authorName"`
+	Body       string     `json:"body"`
+	Approved   bool       `json:"approved"`
+	Status     string     `json:"status"`
+	ParentID   *uint      `json:"parentId"`
+	RootID     *uint      `json:"rootId"`
+	Depth      int        `json:"depth"`
+	ReplyCount int        `json:"replyCount"`
+	Deleted    bool       `json:"deleted"`
+	EditedAt   *time.Time `json:"editedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	User       *UserDTO   `json:"user,omitempty"`
+	// RepliesCursor is set on a top-level comment whose thread has more
+	// replies than were returned; it fetches the rest of them.
+	RepliesCursor *string `json:"repliesCursor,omitempty"`
+}
+
+// CommentNodeDTO is a comment with its replies nested beneath it.
+type CommentNodeDTO struct {
+	CommentDTO
+	Replies []CommentNodeDTO `json:"replies"`
 }
 
 type PostDTO struct {
-	ID          uint         `json:"id"`
-	Title       string       `json:"title"`
-	Summary     string       `json:"summary"`
-	Content     string       `json:"content"`
-	Slug        string       `json:"slug"`
-	Status      string       `json:"status"`
-	CoverImage  string       `json:"coverImage,omitempty"`
-	PublishedAt *time.Time   `json:"publishedAt,omitempty"`
-	Author      UserDTO      `json:"author"`
-	Category    *CategoryDTO `json:"category,omitempty"`
-	Tags        []TagDTO     `json:"tags"`
-	Comments    []CommentDTO `json:"comments,omitempty"`
-	CreatedAt   time.Time    `json:"createdAt"`
-	UpdatedAt   time.Time    `json:"updatedAt"`
+	ID           uint               `json:"id"`
+	Title        string             `json:"title"`
+	Summary      string             `json:"summary"`
+	Content      string             `json:"content"`
+	ContentHTML  string             `json:"contentHtml,omitempty"`
+	TOC          []markdown.Heading `json:"toc,omitempty"`
+	Slug         string             `json:"slug"`
+	Status       string             `json:"status"`
+	Visibility   string             `json:"visibility"`
+	Locked       bool               `json:"locked,omitempty"`
+	CoverImage   string             `json:"coverImage,omitempty"`
+	PublishedAt  *time.Time         `json:"publishedAt,omitempty"`
+	Author       UserDTO            `json:"author"`
+	Category     *CategoryDTO       `json:"category,omitempty"`
+	Tags         []TagDTO           `json:"tags"`
+	CommentCount *int64             `json:"commentCount,omitempty"`
+	CreatedAt    time.Time          `json:"createdAt"`
+	UpdatedAt    time.Time          `json:"updatedAt"`
 }
 
 func buildUserDTO(user models.User) UserDTO {
@@ -64,16 +98,28 @@ func buildUserDTO(user models.User) UserDTO {
 	}
 
 	return UserDTO{
-		ID:          user.ID,
-		Username:    user.Username,
-		Email:       email,
-		DisplayName: user.DisplayName,
-		Bio:         user.Bio,
-		AvatarURL:   user.AvatarURL,
-		CreatedAt:   user.CreatedAt,
+		ID:              user.ID,
+		Username:        user.Username,
+		Email:           email,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		Role:            user.Role,
+		MFAEnabled:      user.MFAEnabled(),
+		DisplayName:     user.DisplayName,
+		Bio:             user.Bio,
+		AvatarURL:       user.AvatarURL,
+		CreatedAt:       user.CreatedAt,
 	}
 }
 
+// buildPublicUserDTO strips account details that only the user should see.
+func buildPublicUserDTO(user models.User) UserDTO {
+	dto := buildUserDTO(user)
+	dto.Email = ""
+	dto.EmailVerifiedAt = nil
+	dto.MFAEnabled = false
+	return dto
+}
+
 func buildCategoryDTO(category *models.Category) *CategoryDTO {
 	if category == nil {
 		return nil
@@ -104,10 +150,24 @@ func buildTagDTOs(tags []models.Tag) []TagDTO {
 func buildCommentDTOs(comments []models.Comment) []CommentDTO {
 	result := make([]CommentDTO, 0, len(comments))
 	for _, comment := range comments {
+		if comment.IsTombstone() {
+			result = append(result, CommentDTO{
+				ID:         comment.ID,
+				Approved:   comment.IsApproved(),
+				Status:     comment.Status,
+				ParentID:   comment.ParentID,
+				RootID:     comment.RootID,
+				Depth:      comment.Depth,
+				ReplyCount: comment.ReplyCount,
+				Deleted:    true,
+				CreatedAt:  comment.CreatedAt,
+			})
+			continue
+		}
+
 		var userDTO *UserDTO
 		if comment.User != nil {
-			dto := buildUserDTO(*comment.User)
-			dto.Email = ""
+			dto := buildPublicUserDTO(*comment.User)
 			userDTO = &dto
 		}
 
@@ -115,7 +175,13 @@ func buildCommentDTOs(comments []models.Comment) []CommentDTO {
 			ID:         comment.ID,
 			AuthorName: comment.AuthorName,
 			Body:       comment.Body,
-			Approved:   comment.Approved,
+			Approved:   comment.IsApproved(),
+			Status:     comment.Status,
+			ParentID:   comment.ParentID,
+			RootID:     comment.RootID,
+			Depth:      comment.Depth,
+			ReplyCount: comment.ReplyCount,
+			EditedAt:   comment.EditedAt,
 			CreatedAt:  comment.CreatedAt,
 			User:       userDTO,
 		})
@@ -123,22 +189,57 @@ func buildCommentDTOs(comments []models.Comment) []CommentDTO {
 	return result
 }
 
+// buildCommentTree nests threaded comment DTOs under their parents. It expects
+// the order produced by threadComments, where parents come before replies.
+func buildCommentTree(dtos []CommentDTO) []CommentNodeDTO {
+	children := make(map[uint][]int)
+	var roots []int
+	index := make(map[uint]int, len(dtos))
+	for i, dto := range dtos {
+		index[dto.ID] = i
+		if dto.ParentID != nil {
+			if _, ok := index[*dto.ParentID]; ok {
+				children[*dto.ParentID] = append(children[*dto.ParentID], i)
+				continue
+			}
+		}
+		roots = append(roots, i)
+	}
+
+	var build func(positions []int) []CommentNodeDTO
+	build = func(positions []int) []CommentNodeDTO {
+		nodes := make([]CommentNodeDTO, 0, len(positions))
+		for _, i := range positions {
+			nodes = append(nodes, CommentNodeDTO{
+				CommentDTO: dtos[i],
+				Replies:    build(children[dtos[i].ID]),
+			})
+		}
+		return nodes
+	}
+	return build(roots)
+}
+
 func buildPostDTO(post models.Post, includeContent bool) PostDTO {
-	content := post.Content
+	content, contentHTML, toc := post.Content, post.ContentHTML, post.TOC
 	if !includeContent {
-		content = ""
+		content, contentHTML, toc = "", "", nil
+	} else if toc == nil {
+		toc = []markdown.Heading{}
 	}
 
-	author := buildUserDTO(post.Author)
-	author.Email = ""
+	author := buildPublicUserDTO(post.Author)
 
 	dto := PostDTO{
 		ID:          post.ID,
 		Title:       post.Title,
 		Summary:     post.Summary,
 		Content:     content,
+		ContentHTML: contentHTML,
+		TOC:         toc,
 		Slug:        post.Slug,
 		Status:      post.Status,
+		Visibility:  post.Visibility,
 		CoverImage:  post.CoverImage,
 		PublishedAt: post.PublishedAt,
 		Author:      author,
@@ -148,9 +249,5 @@ func buildPostDTO(post models.Post, includeContent bool) PostDTO {
 		UpdatedAt:   post.UpdatedAt,
 	}
 
-	if len(post.Comments) > 0 {
-		dto.Comments = buildCommentDTOs(post.Comments)
-	}
-
 	return dto
 }
@@ -1,11 +1,13 @@
 package controllers
 
 import (
+	"errors"
+	"net/http"
 	"strings"
 
 	"gogogo/global"
+	"gogogo/middleware"
 	"gogogo/models"
-	"gogogo/utils"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -36,24 +38,75 @@ func optionalUserID(ctx *gin.Context) (uint, bool) {
 		return id, true
 	}
 
-	rawHeader := ctx.GetHeader("Authorization")
-	if rawHeader == "" {
+	if err := middleware.Authenticate(ctx); err != nil {
 		return 0, false
 	}
 
-	token := rawHeader
-	if parts := strings.SplitN(rawHeader, " ", 2); len(parts) == 2 {
-		token = parts[1]
+	return currentUserID(ctx)
+}
+
+func currentSessionID(ctx *gin.Context) uint {
+	if id, ok := ctx.Get("sessionID"); ok {
+		if sessionID, ok := id.(uint); ok {
+			return sessionID
+		}
 	}
+	return 0
+}
 
-	claims, err := utils.ValidateJWT(token)
-	if err != nil {
-		return 0, false
+func currentUserRole(ctx *gin.Context) string {
+	return ctx.GetString("role")
+}
+
+// canManagePost reports whether the current user may edit or delete the post:
+// its author, or anyone whose role allows editing other people's posts.
+func canManagePost(ctx *gin.Context, post models.Post) bool {
+	userID, ok := optionalUserID(ctx)
+	if !ok {
+		return false
+	}
+	if userID == post.AuthorID {
+		return true
+	}
+	return models.RoleHasPermission(currentUserRole(ctx), models.PermissionEditOthersPosts)
+}
+
+// canModerateComments reports whether the current user may moderate comments
+// on a post by postAuthorID: the post's author, or a comment moderator.
+func canModerateComments(ctx *gin.Context, postAuthorID uint) bool {
+	userID, ok := optionalUserID(ctx)
+	if !ok || !middleware.HasScope(ctx, models.ScopeCommentsModerate) {
+		return false
+	}
+	if userID == postAuthorID {
+		return true
 	}
+	return models.RoleHasPermission(currentUserRole(ctx), models.PermissionModerateComments)
+}
+
+func loadUserByUsername(username string) (*models.User, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	var user models.User
+	if err := global.Db.Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
 
-	ctx.Set("userID", claims.UserID)
-	ctx.Set("username", claims.Username)
-	return claims.UserID, true
+func respondUserSuspended(ctx *gin.Context) {
+	ctx.JSON(http.StatusForbidden, gin.H{"error": middleware.ErrUserSuspended.Error()})
+}
+
+func handleUserLoadError(ctx *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load user"})
 }
 
 func loadUserByID(_ *gin.Context, id uint) (*models.User, error) {
@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gogogo/config"
+	"gogogo/global"
+	"gogogo/models"
+	"gogogo/search"
+	"gogogo/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const testFrontendURL = "http://frontend.test"
+
+// allTestModels is every table config.InitDB migrates.
+var allTestModels = []interface{}{
+	&models.User{},
+	&models.Category{},
+	&models.Tag{},
+	&models.Post{},
+	&models.Comment{},
+	&models.Session{},
+	&models.UserToken{},
+	&models.PersonalAccessToken{},
+	&models.LoginThrottle{},
+	&models.RecoveryCode{},
+	&models.UserIdentity{},
+	&models.PostRevision{},
+	&models.SlugRedirect{},
+	&models.PostPreview{},
+	&models.PostPreviewView{},
+	&models.PostTransition{},
+}
+
+// setupTestDB resets the configuration and points global.Db at an in-memory
+// database private to the test, migrated for the given models.
+func setupTestDB(t *testing.T, tables ...interface{}) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	config.AppConfig = &config.Config{}
+	config.AppConfig.App.Name = "gogogo"
+	config.AppConfig.App.FrontendURL = testFrontendURL
+	config.AppConfig.Auth.JWTSecret = "test-secret"
+	config.AppConfig.Auth.DefaultRole = models.RoleAuthor
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := db.AutoMigrate(tables...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	global.Db = db
+	global.Search = search.NewMemoryEngine()
+}
+
+// createTestPost stores a post by author with the given status.
+func createTestPost(t *testing.T, author models.User, title, status string) models.Post {
+	t.Helper()
+
+	post := models.Post{
+		Title:      title,
+		Content:    title + " body",
+		Slug:       utils.Slugify(title),
+		Status:     status,
+		Visibility: models.PostVisibilityPublic,
+		AuthorID:   author.ID,
+	}
+	if status == models.PostStatusPublished {
+		publishedAt := time.Now().Add(-time.Hour)
+		post.PublishedAt = &publishedAt
+	}
+	if err := post.RenderContent(); err != nil {
+		t.Fatalf("render post: %v", err)
+	}
+	if err := global.Db.Create(&post).Error; err != nil {
+		t.Fatalf("create post: %v", err)
+	}
+	return post
+}
+
+// createTestUser stores a user with the given role; the password is not a
+// valid hash, so it cannot log in.
+func createTestUser(t *testing.T, username, role string) models.User {
+	t.Helper()
+
+	user := models.User{
+		Username:    username,
+		Password:    "not-a-real-hash",
+		Role:        role,
+		DisplayName: username,
+	}
+	if err := global.Db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// asUser makes every request through router act as user, the way
+// AuthMiddleware does for a session login.
+func asUser(router *gin.Engine, user models.User) {
+	router.Use(func(ctx *gin.Context) {
+		ctx.Set("userID", user.ID)
+		ctx.Set("username", user.Username)
+		ctx.Set("role", user.Role)
+		ctx.Next()
+	})
+}
+
+// serveJSON sends body as JSON to the router and decodes the response.
+func serveJSON(t *testing.T, router *gin.Engine, method, path string, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatalf("encode body: %v", err)
+		}
+	}
+	request := httptest.NewRequest(method, path, &payload)
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	var response map[string]interface{}
+	if recorder.Body.Len() > 0 {
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s %s: bad JSON %q", method, path, recorder.Body.String())
+		}
+	}
+	return recorder.Code, response
+}
+
+func writeTestJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(value)
+}
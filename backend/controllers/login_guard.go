@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gogogo/config"
+	"gogogo/global"
+	"gogogo/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// loginBlock describes why a login attempt is refused before the password is
+// even checked.
+type loginBlock struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func usernameThrottleKey(username string) string {
+	return "user:" + strings.ToLower(strings.TrimSpace(username))
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+func loginThrottleKeys(ctx *gin.Context, username string) []string {
+	return []string{usernameThrottleKey(username), ipThrottleKey(ctx.ClientIP())}
+}
+
+// passwordResetThrottleKeys count reset requests per address and per client
+// IP, apart from the login counters so neither can lock out the other.
+func passwordResetThrottleKeys(ctx *gin.Context, email string) []string {
+	return []string{
+		"reset:email:" + strings.ToLower(strings.TrimSpace(email)),
+		"reset:" + ipThrottleKey(ctx.ClientIP()),
+	}
+}
+
+func isIPThrottleKey(key string) bool {
+	return strings.HasPrefix(strings.TrimPrefix(key, "reset:"), "ip:")
+}
+
+// checkLoginAllowed returns a non-nil block while any key is locked out or
+// still inside its backoff window.
+func checkLoginAllowed(keys []string) (*loginBlock, error) {
+	var throttles []models.LoginThrottle
+	if err := global.Db.Where("throttle_key IN ?", keys).Find(&throttles).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var block *loginBlock
+	for _, throttle := range throttles {
+		var wait time.Duration
+		locked := false
+		if throttle.LockedUntil != nil && now.Before(*throttle.LockedUntil) {
+			wait = throttle.LockedUntil.Sub(now)
+			locked = strings.HasPrefix(throttle.Key, "user:")
+		} else if throttle.Failures > 0 {
+			wait = throttle.LastFailedAt.Add(loginBackoff(throttle.Failures)).Sub(now)
+		}
+
+		if wait <= 0 {
+			continue
+		}
+		if block == nil {
+			block = &loginBlock{}
+		}
+		block.RetryAfter = max(block.RetryAfter, wait)
+		block.Locked = block.Locked || locked
+	}
+
+	return block, nil
+}
+
+// recordLoginFailure bumps every key and locks those that reach their limit.
+// Failures older than the lockout window no longer count. Each key is bumped
+// with a single upsert, which also keeps its row locked until the transaction
+// ends, so concurrent failures are neither lost nor collide on a new key.
+func recordLoginFailure(keys []string) error {
+	now := time.Now()
+	window := loginLockoutDuration()
+
+	return global.Db.Transaction(func(tx *gorm.DB) error {
+		for _, key := range keys {
+			// Assignments run in order, so failures is computed from the
+			// previous last_failed_at.
+			bump := clause.Set{
+				{Column: clause.Column{Name: "failures"}, Value: gorm.Expr("CASE WHEN last_failed_at < ? THEN 1 ELSE failures + 1 END", now.Add(-window))},
+				{Column: clause.Column{Name: "last_failed_at"}, Value: now},
+				{Column: clause.Column{Name: "updated_at"}, Value: now},
+			}
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "throttle_key"}},
+				DoUpdates: bump,
+			}).Create(&models.LoginThrottle{Key: key, Failures: 1, LastFailedAt: now}).Error
+			if err != nil {
+				return err
+			}
+
+			var throttle models.LoginThrottle
+			if err := tx.Where("throttle_key = ?", key).First(&throttle).Error; err != nil {
+				return err
+			}
+			if throttle.Failures < loginAttemptLimit(key) {
+				continue
+			}
+
+			lockedUntil := now.Add(window)
+			if err := tx.Model(&throttle).Updates(map[string]interface{}{
+				"failures":     0,
+				"locked_until": lockedUntil,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// clearLoginFailures forgets failures for a username after a good login. The
+// IP counter is left alone so one valid account cannot reset it.
+func clearLoginFailures(username string) error {
+	return global.Db.Unscoped().
+		Where("throttle_key = ?", usernameThrottleKey(username)).
+		Delete(&models.LoginThrottle{}).Error
+}
+
+func respondLoginBlocked(ctx *gin.Context, block *loginBlock) {
+	seconds := int(math.Ceil(block.RetryAfter.Seconds()))
+	ctx.Header("Retry-After", strconv.Itoa(seconds))
+
+	if block.Locked {
+		ctx.JSON(http.StatusLocked, gin.H{
+			"error":      "account temporarily locked after too many failed logins",
+			"retryAfter": seconds,
+		})
+		return
+	}
+	ctx.JSON(http.StatusTooManyRequests, gin.H{
+		"error":      "too many login attempts, slow down",
+		"retryAfter": seconds,
+	})
+}
+
+func loginBackoff(failures int) time.Duration {
+	authConfig := config.AppConfig.Auth
+	base := time.Duration(max(authConfig.LoginBackoffBaseSeconds, 0)) * time.Second
+	ceiling := time.Duration(max(authConfig.LoginBackoffMaxSeconds, 0)) * time.Second
+	if base == 0 || failures <= 0 {
+		return 0
+	}
+
+	backoff := base << min(failures-1, 20)
+	if ceiling > 0 && backoff > ceiling {
+		return ceiling
+	}
+	return backoff
+}
+
+func loginAttemptLimit(key string) int {
+	limit := config.AppConfig.Auth.LoginMaxAttempts
+	if isIPThrottleKey(key) {
+		limit = config.AppConfig.Auth.LoginIPMaxAttempts
+	}
+	if limit <= 0 {
+		return math.MaxInt
+	}
+	return limit
+}
+
+func loginLockoutDuration() time.Duration {
+	minutes := config.AppConfig.Auth.LoginLockoutMinutes
+	if minutes <= 0 {
+		minutes = 15
+	}
+	return time.Duration(minutes) * time.Minute
+}
@@ -0,0 +1,367 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"gogogo/config"
+	"gogogo/global"
+	"gogogo/models"
+	"gogogo/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	mfaChallengeTTL   = 5 * time.Minute
+	recoveryCodeCount = 10
+)
+
+type confirmTOTPRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type passwordConfirmationRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+type mfaLoginRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recoveryCode"`
+}
+
+// BeginTOTPEnrollment stores a pending secret. It only takes effect once the
+// user proves their authenticator works via ConfirmTOTPEnrollment.
+func BeginTOTPEnrollment(ctx *gin.Context) {
+	user, ok := loadCurrentUser(ctx)
+	if !ok {
+		return
+	}
+
+	if user.MFAEnabled() {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, uri, err := utils.GenerateTOTPKey(config.AppConfig.App.Name, user.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate secret"})
+		return
+	}
+
+	if err := global.Db.Model(user).Updates(map[string]interface{}{
+		"totp_secret":     secret,
+		"totp_enabled_at": nil,
+		"totp_last_step":  0,
+	}).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store secret"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"secret":          secret,
+		"provisioningUri": uri,
+	})
+}
+
+func ConfirmTOTPEnrollment(ctx *gin.Context) {
+	user, ok := loadCurrentUser(ctx)
+	if !ok {
+		return
+	}
+
+	var input confirmTOTPRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if user.MFAEnabled() {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "two-factor authentication is already enabled"})
+		return
+	}
+	if user.TOTPSecret == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "start enrollment first"})
+		return
+	}
+
+	step, valid := utils.MatchTOTPStep(user.TOTPSecret, input.Code, time.Now())
+	if !valid {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid code"})
+		return
+	}
+
+	var codes []string
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"totp_enabled_at": time.Now(),
+			"totp_last_step":  step,
+		}).Error; err != nil {
+			return err
+		}
+
+		var genErr error
+		codes, genErr = replaceRecoveryCodes(tx, user.ID)
+		return genErr
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to enable two-factor authentication"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"recoveryCodes": codes})
+}
+
+func DisableTOTP(ctx *gin.Context) {
+	user, ok := loadCurrentUser(ctx)
+	if !ok {
+		return
+	}
+
+	var input passwordConfirmationRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !utils.CheckPassword(input.Password, user.Password) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "password is incorrect"})
+		return
+	}
+
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"totp_secret":     "",
+			"totp_enabled_at": nil,
+			"totp_last_step":  0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to disable two-factor authentication"})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func RegenerateRecoveryCodes(ctx *gin.Context) {
+	user, ok := loadCurrentUser(ctx)
+	if !ok {
+		return
+	}
+
+	var input passwordConfirmationRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !user.MFAEnabled() {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "two-factor authentication is not enabled"})
+		return
+	}
+	if !utils.CheckPassword(input.Password, user.Password) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "password is incorrect"})
+		return
+	}
+
+	var codes []string
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		var genErr error
+		codes, genErr = replaceRecoveryCodes(tx, user.ID)
+		return genErr
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate recovery codes"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"recoveryCodes": codes})
+}
+
+// CompleteMFALogin exchanges the challenge issued by Login plus a TOTP or
+// recovery code for a real session.
+func CompleteMFALogin(ctx *gin.Context) {
+	var input mfaLoginRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.Code == "" && input.RecoveryCode == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "code or recoveryCode is required"})
+		return
+	}
+
+	claims, err := utils.ValidateActionToken(input.ChallengeToken, models.TokenPurposeMFAChallenge)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired challenge"})
+		return
+	}
+	userID, err := claims.SubjectID()
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired challenge"})
+		return
+	}
+
+	user, err := loadUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired challenge"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load user"})
+		return
+	}
+
+	if !user.MFAEnabled() {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired challenge"})
+		return
+	}
+	if user.IsSuspended() {
+		respondUserSuspended(ctx)
+		return
+	}
+
+	throttleKeys := loginThrottleKeys(ctx, user.Username)
+	block, err := checkLoginAllowed(throttleKeys)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check login attempts"})
+		return
+	}
+	if block != nil {
+		respondLoginBlocked(ctx, block)
+		return
+	}
+
+	var verified bool
+	if input.Code != "" {
+		verified, err = consumeTOTPCode(user, input.Code)
+	} else {
+		verified, err = consumeRecoveryCode(user.ID, input.RecoveryCode)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify code"})
+		return
+	}
+	if !verified {
+		if err := recordLoginFailure(throttleKeys); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record login attempt"})
+			return
+		}
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid code"})
+		return
+	}
+
+	if err := clearLoginFailures(user.Username); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reset login attempts"})
+		return
+	}
+
+	tokens, err := issueSession(ctx, *user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, authResponse(*user, tokens))
+}
+
+// respondMFAChallenge answers a correct password for an account with TOTP
+// enabled. The challenge token alone grants nothing.
+func respondMFAChallenge(ctx *gin.Context, user models.User) {
+	challenge, expiresAt, err := issueMFAChallenge(user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create challenge"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"mfaRequired":    true,
+		"challengeToken": challenge,
+		"expiresAt":      expiresAt,
+	})
+}
+
+func issueMFAChallenge(user models.User) (string, time.Time, error) {
+	challengeID, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	challenge, err := utils.GenerateActionToken(models.TokenPurposeMFAChallenge, user.ID, challengeID, mfaChallengeTTL)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return challenge, time.Now().Add(mfaChallengeTTL), nil
+}
+
+// consumeTOTPCode accepts each time step at most once per user.
+func consumeTOTPCode(user *models.User, code string) (bool, error) {
+	step, valid := utils.MatchTOTPStep(user.TOTPSecret, code, time.Now())
+	if !valid || step <= user.TOTPLastStep {
+		return false, nil
+	}
+
+	result := global.Db.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", user.ID, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func consumeRecoveryCode(userID uint, code string) (bool, error) {
+	hash := utils.HashToken(utils.NormalizeRecoveryCode(code))
+	result := global.Db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := utils.GenerateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		records = append(records, models.RecoveryCode{UserID: userID, CodeHash: utils.HashToken(code)})
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func loadCurrentUser(ctx *gin.Context) (*models.User, bool) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return nil, false
+	}
+
+	user, err := loadUserByID(ctx, userID)
+	if err != nil {
+		handleUserLoadError(ctx, err)
+		return nil, false
+	}
+	return user, true
+}
@@ -0,0 +1,388 @@
+package controllers
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"gogogo/config"
+	"gogogo/global"
+	"gogogo/models"
+	"gogogo/spam"
+	"gogogo/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	commentModerationAutoApprove = "auto_approve"
+	commentModerationHoldGuests  = "hold_guests"
+	commentModerationHoldAll     = "hold_all"
+	commentModerationHoldLinks   = "hold_links"
+)
+
+const (
+	moderationActionApprove = "approve"
+	moderationActionReject  = "reject"
+	moderationActionSpam    = "spam"
+	moderationActionDelete  = "delete"
+)
+
+// maxModerationBatch caps how many comments one bulk action may touch.
+const maxModerationBatch = 100
+
+// maxSpamReasonsLength matches the size of Comment.SpamReasons.
+const maxSpamReasonsLength = 512
+
+var commentLinkPattern = regexp.MustCompile(`(?i)https?://|www\.|<a\s`)
+
+// moderationActionStatuses maps the status-changing actions to the status
+// they set; delete is handled separately.
+var moderationActionStatuses = map[string]string{
+	moderationActionApprove: models.CommentStatusApproved,
+	moderationActionReject:  models.CommentStatusRejected,
+	moderationActionSpam:    models.CommentStatusSpam,
+}
+
+// moderationActionLabels maps the actions that teach the spam filter to the
+// label they train. Rejected comments are off-topic rather than spam, so
+// rejecting teaches nothing.
+var moderationActionLabels = map[string]string{
+	moderationActionApprove: spam.LabelHam,
+	moderationActionSpam:    spam.LabelSpam,
+}
+
+type moderateCommentsRequest struct {
+	IDs    []uint `json:"ids" binding:"required"`
+	Action string `json:"action" binding:"required"`
+}
+
+type ModerationPostDTO struct {
+	ID    uint   `json:"id"`
+	Title string `json:"title"`
+	Slug  string `json:"slug"`
+}
+
+type ModerationCommentDTO struct {
+	CommentDTO
+	Post        ModerationPostDTO `json:"post"`
+	IPAddress   string            `json:"ipAddress"`
+	UserAgent   string            `json:"userAgent"`
+	SpamScore   float64           `json:"spamScore"`
+	SpamReasons []string          `json:"spamReasons"`
+	// PreviousBody is the body before the latest edit, if it was edited.
+	PreviousBody string `json:"previousBody,omitempty"`
+}
+
+// initialCommentStatus applies comments.moderation to a new comment. The
+// post's author and comment moderators are never held.
+func initialCommentStatus(ctx *gin.Context, post models.Post, comment models.Comment) string {
+	if comment.UserID != nil && canModerateComments(ctx, post.AuthorID) {
+		return models.CommentStatusApproved
+	}
+
+	switch config.AppConfig.Comments.Moderation {
+	case commentModerationAutoApprove:
+		return models.CommentStatusApproved
+	case commentModerationHoldAll:
+		return models.CommentStatusPending
+	case commentModerationHoldLinks:
+		if commentLinkPattern.MatchString(comment.Body) {
+			return models.CommentStatusPending
+		}
+		return models.CommentStatusApproved
+	default:
+		if comment.UserID == nil {
+			return models.CommentStatusPending
+		}
+		return models.CommentStatusApproved
+	}
+}
+
+// checkCommentSpam scores a new or edited comment with the spam filter, holding it or
+// marking it as spam when the score is high enough. Comments that
+// initialCommentStatus always approves are not scored. Edited comments are
+// not counted against themselves.
+func checkCommentSpam(ctx *gin.Context, post models.Post, comment *models.Comment) (spam.Verdict, error) {
+	if global.Spam == nil || (comment.UserID != nil && canModerateComments(ctx, post.AuthorID)) {
+		return spam.Allow, nil
+	}
+
+	settings := config.AppConfig.Comments.Spam
+	candidate := spam.Comment{
+		Body:      comment.Body,
+		IP:        comment.IPAddress,
+		UserAgent: comment.UserAgent,
+	}
+	now := time.Now()
+	if settings.DuplicateWindowHours > 0 {
+		var duplicates int64
+		if err := global.Db.Unscoped().Model(&models.Comment{}).
+			Where("id <> ? AND body_hash = ? AND created_at >= ?", comment.ID, comment.BodyHash, now.Add(-time.Duration(settings.DuplicateWindowHours)*time.Hour)).
+			Count(&duplicates).Error; err != nil {
+			return spam.Allow, err
+		}
+		candidate.Duplicates = int(duplicates)
+	}
+	if settings.MaxPerMinute > 0 && comment.IPAddress != "" {
+		var recent int64
+		if err := global.Db.Unscoped().Model(&models.Comment{}).
+			Where("id <> ? AND ip_address = ? AND created_at >= ?", comment.ID, comment.IPAddress, now.Add(-time.Minute)).
+			Count(&recent).Error; err != nil {
+			return spam.Allow, err
+		}
+		candidate.RecentFromIP = int(recent)
+	}
+
+	result, err := global.Spam.Check(candidate)
+	if err != nil {
+		return spam.Allow, err
+	}
+	comment.SpamScore = result.Score
+	comment.SpamReasons = joinSpamReasons(result.Reasons)
+	switch result.Verdict {
+	case spam.Reject:
+		comment.Status = models.CommentStatusSpam
+	case spam.Hold:
+		if comment.Status == models.CommentStatusApproved {
+			comment.Status = models.CommentStatusPending
+		}
+	}
+	return result.Verdict, nil
+}
+
+// joinSpamReasons joins as many whole reasons as fit in the column.
+func joinSpamReasons(reasons []string) string {
+	joined := ""
+	for _, reason := range reasons {
+		next := reason
+		if joined != "" {
+			next = joined + "; " + reason
+		}
+		if len(next) > maxSpamReasonsLength {
+			break
+		}
+		joined = next
+	}
+	return joined
+}
+
+// trainSpamFilter teaches the spam filter the label a moderator gave these
+// comments. A comment trained before under the other label is forgotten
+// first, so changing a decision does not count it twice.
+func trainSpamFilter(tx *gorm.DB, comments []models.Comment, label string) error {
+	if global.Spam == nil {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(comments))
+	for _, comment := range comments {
+		if comment.SpamLabel == label {
+			continue
+		}
+		if comment.SpamLabel != "" {
+			if err := global.Spam.Forget(tx, comment.Body, comment.SpamLabel); err != nil {
+				return err
+			}
+		}
+		if err := global.Spam.Train(tx, comment.Body, label); err != nil {
+			return err
+		}
+		ids = append(ids, comment.ID)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return tx.Model(&models.Comment{}).Where("id IN ?", ids).UpdateColumn("spam_label", label).Error
+}
+
+// ListModerationComments lists comments the current user may moderate,
+// filtered by ?status= (pending by default) and optionally ?postId=. Pending
+// comments come oldest first so the queue is worked in order; other
+// statuses show the most recent first.
+func ListModerationComments(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	status := ctx.DefaultQuery("status", models.CommentStatusPending)
+	switch status {
+	case models.CommentStatusPending, models.CommentStatusApproved,
+		models.CommentStatusRejected, models.CommentStatusSpam:
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+		return
+	}
+
+	posts := global.Db.Model(&models.Post{}).Select("id")
+	if !models.RoleHasPermission(currentUserRole(ctx), models.PermissionModerateComments) {
+		posts = posts.Where("author_id = ?", userID)
+	}
+	if postID := ctx.Query("postId"); postID != "" {
+		posts = posts.Where("id = ?", postID)
+	}
+
+	page, pageSize := utils.GetPagination(ctx)
+	comments := global.Db.Model(&models.Comment{}).
+		Where("status = ? AND post_id IN (?)", status, posts)
+
+	var total int64
+	if err := comments.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count comments"})
+		return
+	}
+
+	order := "created_at DESC"
+	if status == models.CommentStatusPending {
+		order = "created_at ASC"
+	}
+
+	var result []models.Comment
+	if err := comments.Session(&gorm.Session{}).
+		Preload("User").
+		Preload("Post", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "title", "slug")
+		}).
+		Order(order).
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&result).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load comments"})
+		return
+	}
+
+	dtos := buildCommentDTOs(result)
+	response := make([]ModerationCommentDTO, 0, len(result))
+	for i, comment := range result {
+		response = append(response, ModerationCommentDTO{
+			CommentDTO: dtos[i],
+			Post: ModerationPostDTO{
+				ID:    comment.Post.ID,
+				Title: comment.Post.Title,
+				Slug:  comment.Post.Slug,
+			},
+			IPAddress:    comment.IPAddress,
+			UserAgent:    comment.UserAgent,
+			SpamScore:    comment.SpamScore,
+			SpamReasons:  splitSpamReasons(comment.SpamReasons),
+			PreviousBody: comment.PreviousBody,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":     response,
+		"page":     page,
+		"pageSize": pageSize,
+		"total":    total,
+	})
+}
+
+// ModerateComments applies one action to a batch of comments. The batch is
+// all or nothing: an unknown comment or one on somebody else's post rejects
+// the whole request. Approving and marking as spam also train the spam
+// filter.
+func ModerateComments(ctx *gin.Context) {
+	var input moderateCommentsRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status, setsStatus := moderationActionStatuses[input.Action]
+	if !setsStatus && input.Action != moderationActionDelete {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "action must be approve, reject, spam or delete"})
+		return
+	}
+
+	ids := uniqueIDs(input.IDs)
+	if len(ids) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "ids must not be empty"})
+		return
+	}
+	if len(ids) > maxModerationBatch {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "too many comments in one request"})
+		return
+	}
+
+	var comments []models.Comment
+	if err := global.Db.
+		Preload("Post", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped().Select("id", "author_id")
+		}).
+		Where("id IN ?", ids).
+		Find(&comments).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load comments"})
+		return
+	}
+	if len(comments) != len(ids) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "comment not found"})
+		return
+	}
+	for _, comment := range comments {
+		if !canModerateComments(ctx, comment.Post.AuthorID) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "not allowed to moderate these comments"})
+			return
+		}
+	}
+
+	parents := make([]uint, 0, len(comments))
+	for _, comment := range comments {
+		if comment.ParentID != nil {
+			parents = append(parents, *comment.ParentID)
+		}
+	}
+
+	moderatorID, _ := currentUserID(ctx)
+	now := time.Now()
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		if setsStatus {
+			if err := tx.Model(&models.Comment{}).
+				Where("id IN ?", ids).
+				Updates(map[string]interface{}{
+					"status":          status,
+					"moderated_by_id": moderatorID,
+					"moderated_at":    now,
+				}).Error; err != nil {
+				return err
+			}
+		} else if err := tx.Where("id IN ?", ids).Delete(&models.Comment{}).Error; err != nil {
+			return err
+		}
+		if label, ok := moderationActionLabels[input.Action]; ok {
+			if err := trainSpamFilter(tx, comments, label); err != nil {
+				return err
+			}
+		}
+		return models.RecountReplies(tx, uniqueIDs(parents))
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to moderate comments"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"action": input.Action, "count": len(ids)})
+}
+
+func splitSpamReasons(reasons string) []string {
+	if reasons == "" {
+		return []string{}
+	}
+	return strings.Split(reasons, "; ")
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
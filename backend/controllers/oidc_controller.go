@@ -0,0 +1,321 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gogogo/config"
+	"gogogo/global"
+	"gogogo/models"
+	"gogogo/oidc"
+	"gogogo/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	oidcStateCookie = "oidc_state"
+	oidcStateTTL    = 10 * time.Minute
+)
+
+var (
+	errOIDCEmailUnverified = errors.New("email_not_verified")
+	errOIDCAccountConflict = errors.New("account_conflict")
+)
+
+type OIDCProviderDTO struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+	LoginURL    string `json:"loginUrl"`
+}
+
+func ListOIDCProviders(ctx *gin.Context) {
+	providers := global.OIDC.List()
+	result := make([]OIDCProviderDTO, 0, len(providers))
+	for _, provider := range providers {
+		result = append(result, OIDCProviderDTO{
+			Name:        provider.Name(),
+			DisplayName: provider.DisplayName(),
+			LoginURL:    "/api/auth/oidc/" + provider.Name() + "/login",
+		})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// BeginOIDCLogin redirects the browser to the identity provider. The state,
+// nonce and PKCE verifier are kept in a signed cookie scoped to the callback.
+func BeginOIDCLogin(ctx *gin.Context) {
+	provider, err := global.OIDC.Get(ctx.Param("provider"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	stateID, err := utils.GenerateRandomToken(24)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start sign-in"})
+		return
+	}
+	nonce, err := utils.GenerateRandomToken(24)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start sign-in"})
+		return
+	}
+
+	state := utils.OIDCState{
+		ID:       stateID,
+		Provider: provider.Name(),
+		Nonce:    nonce,
+		Verifier: oidc.NewVerifier(),
+		Redirect: safeRedirectPath(ctx.Query("redirect")),
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx.Request.Context(), state.ID, state.Nonce, state.Verifier)
+	if err != nil {
+		log.Printf("oidc: %v", err)
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": "identity provider unavailable"})
+		return
+	}
+
+	cookie, err := utils.GenerateOIDCState(state, oidcStateTTL)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start sign-in"})
+		return
+	}
+
+	setOIDCStateCookie(ctx, cookie, int(oidcStateTTL.Seconds()))
+	ctx.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback finishes the code flow and hands the tokens to the frontend in
+// the URL fragment, which browsers never send to servers.
+func OIDCCallback(ctx *gin.Context) {
+	cookie, _ := ctx.Cookie(oidcStateCookie)
+	setOIDCStateCookie(ctx, "", -1)
+
+	state, err := utils.ValidateOIDCState(cookie)
+	if err != nil || state.Provider != strings.ToLower(ctx.Param("provider")) ||
+		subtle.ConstantTimeCompare([]byte(state.ID), []byte(ctx.Query("state"))) != 1 {
+		redirectOIDCResult(ctx, url.Values{"error": {"invalid_state"}})
+		return
+	}
+
+	if providerError := ctx.Query("error"); providerError != "" {
+		redirectOIDCResult(ctx, url.Values{"error": {providerError}})
+		return
+	}
+
+	provider, err := global.OIDC.Get(state.Provider)
+	if err != nil {
+		redirectOIDCResult(ctx, url.Values{"error": {"unknown_provider"}})
+		return
+	}
+
+	identity, err := provider.Exchange(ctx.Request.Context(), ctx.Query("code"), state.Verifier, state.Nonce)
+	if err != nil {
+		log.Printf("oidc %s: %v", provider.Name(), err)
+		redirectOIDCResult(ctx, url.Values{"error": {"exchange_failed"}})
+		return
+	}
+
+	user, err := resolveOIDCUser(provider.Name(), identity)
+	if err != nil {
+		if errors.Is(err, errOIDCEmailUnverified) || errors.Is(err, errOIDCAccountConflict) {
+			redirectOIDCResult(ctx, url.Values{"error": {err.Error()}})
+			return
+		}
+		log.Printf("oidc %s: failed to resolve user: %v", provider.Name(), err)
+		redirectOIDCResult(ctx, url.Values{"error": {"server_error"}})
+		return
+	}
+
+	if user.IsSuspended() {
+		redirectOIDCResult(ctx, url.Values{"error": {"account_suspended"}})
+		return
+	}
+
+	result := url.Values{"redirect": {state.Redirect}}
+	if user.MFAEnabled() {
+		challenge, _, err := issueMFAChallenge(*user)
+		if err != nil {
+			redirectOIDCResult(ctx, url.Values{"error": {"server_error"}})
+			return
+		}
+		result.Set("mfaChallenge", challenge)
+		redirectOIDCResult(ctx, result)
+		return
+	}
+
+	tokens, err := issueSession(ctx, *user)
+	if err != nil {
+		redirectOIDCResult(ctx, url.Values{"error": {"server_error"}})
+		return
+	}
+
+	result.Set("token", tokens.AccessToken)
+	result.Set("refreshToken", tokens.RefreshToken)
+	result.Set("expiresAt", tokens.ExpiresAt.Format(time.RFC3339))
+	redirectOIDCResult(ctx, result)
+}
+
+// resolveOIDCUser finds the user already linked to the identity, links an
+// existing account with the same verified email, or creates a new account.
+// Accounts whose email was never verified locally are not linked, otherwise
+// anyone could pre-register a victim's address and wait for them to sign in.
+func resolveOIDCUser(providerName string, identity *oidc.Identity) (*models.User, error) {
+	var user models.User
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		var link models.UserIdentity
+		err := tx.Where("provider = ? AND subject = ?", providerName, identity.Subject).First(&link).Error
+		if err == nil {
+			if err := tx.First(&user, link.UserID).Error; err != nil {
+				return err
+			}
+			return tx.Model(&link).Updates(map[string]interface{}{
+				"email":         identity.Email,
+				"last_login_at": now,
+			}).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if identity.Email == "" || !identity.EmailVerified {
+			return errOIDCEmailUnverified
+		}
+
+		err = tx.Where("email = ?", identity.Email).First(&user).Error
+		switch {
+		case err == nil:
+			if user.EmailVerifiedAt == nil {
+				return errOIDCAccountConflict
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			created, err := createOIDCUser(tx, identity)
+			if err != nil {
+				return err
+			}
+			user = *created
+		default:
+			return err
+		}
+
+		return tx.Create(&models.UserIdentity{
+			UserID:      user.ID,
+			Provider:    providerName,
+			Subject:     identity.Subject,
+			Email:       identity.Email,
+			LastLoginAt: now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// createOIDCUser registers an account that can only sign in through the
+// provider until the user sets a password via the reset flow.
+func createOIDCUser(tx *gorm.DB, identity *oidc.Identity) (*models.User, error) {
+	username, err := availableUsername(tx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	hashedPwd, err := utils.HashPassword(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	email := identity.Email
+	verifiedAt := time.Now()
+	user := models.User{
+		Username:        username,
+		Email:           &email,
+		EmailVerifiedAt: &verifiedAt,
+		Password:        hashedPwd,
+		Role:            defaultUserRole(),
+		DisplayName:     strings.TrimSpace(identity.Name),
+	}
+	if isHTTPURL(identity.Picture) {
+		user.AvatarURL = identity.Picture
+	}
+	if user.DisplayName == "" {
+		user.DisplayName = user.Username
+	}
+
+	if err := tx.Create(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func availableUsername(tx *gorm.DB, identity *oidc.Identity) (string, error) {
+	base := strings.TrimSpace(identity.PreferredUsername)
+	if base == "" {
+		base, _, _ = strings.Cut(identity.Email, "@")
+	}
+	base = strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '/' {
+			return -1
+		}
+		return r
+	}, base)
+	if base == "" {
+		base = "user"
+	}
+	if len(base) > 48 {
+		base = base[:48]
+	}
+
+	candidate := base
+	for i := 2; i < 100; i++ {
+		var count int64
+		if err := tx.Model(&models.User{}).Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		candidate = base + "-" + strconv.Itoa(i)
+	}
+
+	suffix, err := utils.GenerateRandomToken(6)
+	if err != nil {
+		return "", err
+	}
+	return base + "-" + suffix, nil
+}
+
+// safeRedirectPath only allows local paths so the callback cannot be turned
+// into an open redirect.
+func safeRedirectPath(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.Contains(path, `\`) {
+		return "/dashboard"
+	}
+	return path
+}
+
+func setOIDCStateCookie(ctx *gin.Context, value string, maxAge int) {
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oidcStateCookie, value, maxAge, "/api/auth/oidc", "", ctx.Request.TLS != nil, true)
+}
+
+func redirectOIDCResult(ctx *gin.Context, values url.Values) {
+	base := strings.TrimRight(config.AppConfig.App.FrontendURL, "/")
+	ctx.Redirect(http.StatusFound, base+"/auth/callback#"+values.Encode())
+}
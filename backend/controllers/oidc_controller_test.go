@@ -0,0 +1,323 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"gogogo/global"
+	"gogogo/models"
+	"gogogo/oidc"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	testOIDCProvider = "mock"
+	testOIDCClientID = "gogogo-test"
+)
+
+// mockOIDCProvider is a minimal OpenID Connect issuer: discovery, a JWKS
+// with one RSA key, and a token endpoint that answers each code with the ID
+// token claims registered for it.
+type mockOIDCProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	claims map[string]jwt.MapClaims
+}
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	provider := &mockOIDCProvider{key: key, claims: make(map[string]jwt.MapClaims)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		issuer := provider.server.URL
+		writeTestJSON(w, map[string]interface{}{
+			"issuer":                                issuer,
+			"authorization_endpoint":                issuer + "/authorize",
+			"token_endpoint":                        issuer + "/token",
+			"jwks_uri":                              issuer + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		encode := base64.RawURLEncoding.EncodeToString
+		writeTestJSON(w, map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"alg": "RS256",
+				"use": "sig",
+				"kid": "test",
+				"n":   encode(key.PublicKey.N.Bytes()),
+				"e":   encode(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		provider.mu.Lock()
+		claims, ok := provider.claims[r.FormValue("code")]
+		provider.mu.Unlock()
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "test"
+		idToken, err := token.SignedString(key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeTestJSON(w, map[string]interface{}{
+			"access_token": "access-" + r.FormValue("code"),
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     idToken,
+		})
+	})
+	provider.server = httptest.NewServer(mux)
+	t.Cleanup(provider.server.Close)
+	return provider
+}
+
+// expect makes code redeem to an ID token for subject with the given email
+// claims and nonce.
+func (p *mockOIDCProvider) expect(code, subject, email string, emailVerified bool, nonce string) {
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.claims[code] = jwt.MapClaims{
+		"iss":            p.server.URL,
+		"aud":            testOIDCClientID,
+		"sub":            subject,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"nonce":          nonce,
+		"email":          email,
+		"email_verified": emailVerified,
+		"name":           "Test User",
+	}
+}
+
+func setupOIDCTest(t *testing.T) (*gin.Engine, *mockOIDCProvider) {
+	t.Helper()
+	setupTestDB(t, &models.User{}, &models.UserIdentity{}, &models.Session{})
+
+	provider := newMockOIDCProvider(t)
+	registry, err := oidc.NewRegistry([]oidc.ProviderConfig{{
+		Name:        testOIDCProvider,
+		Issuer:      provider.server.URL,
+		ClientID:    testOIDCClientID,
+		RedirectURL: "http://api.test/api/auth/oidc/mock/callback",
+	}})
+	if err != nil {
+		t.Fatalf("registry: %v", err)
+	}
+	global.OIDC = registry
+
+	router := gin.New()
+	router.GET("/api/auth/oidc/:provider/login", BeginOIDCLogin)
+	router.GET("/api/auth/oidc/:provider/callback", OIDCCallback)
+	return router, provider
+}
+
+// oidcLogin starts a sign-in and returns the state cookie together with the
+// state and nonce sent to the provider.
+func oidcLogin(t *testing.T, router *gin.Engine) (*http.Cookie, string, string) {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/mock/login?redirect=/dashboard/posts", nil))
+	if recorder.Code != http.StatusFound {
+		t.Fatalf("login: status %d, body %s", recorder.Code, recorder.Body.String())
+	}
+
+	location, err := url.Parse(recorder.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("login: bad redirect: %v", err)
+	}
+	var cookie *http.Cookie
+	for _, candidate := range recorder.Result().Cookies() {
+		if candidate.Name == oidcStateCookie {
+			cookie = candidate
+		}
+	}
+	if cookie == nil {
+		t.Fatal("login: no state cookie")
+	}
+	return cookie, location.Query().Get("state"), location.Query().Get("nonce")
+}
+
+// oidcCallback returns the values the callback hands to the frontend.
+func oidcCallback(t *testing.T, router *gin.Engine, cookie *http.Cookie, state, code string) url.Values {
+	t.Helper()
+
+	query := url.Values{"state": {state}, "code": {code}}
+	request := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/mock/callback?"+query.Encode(), nil)
+	if cookie != nil {
+		request.AddCookie(cookie)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusFound {
+		t.Fatalf("callback: status %d, body %s", recorder.Code, recorder.Body.String())
+	}
+
+	location, err := url.Parse(recorder.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("callback: bad redirect: %v", err)
+	}
+	if got := location.Scheme + "://" + location.Host + location.Path; got != testFrontendURL+"/auth/callback" {
+		t.Fatalf("callback: redirected to %s", got)
+	}
+	values, err := url.ParseQuery(location.Fragment)
+	if err != nil {
+		t.Fatalf("callback: bad fragment: %v", err)
+	}
+	return values
+}
+
+func TestOIDCCallbackRejectsStateMismatch(t *testing.T) {
+	router, provider := setupOIDCTest(t)
+	cookie, _, nonce := oidcLogin(t, router)
+	provider.expect("code-1", "subject-1", "alice@example.com", true, nonce)
+
+	result := oidcCallback(t, router, cookie, "not-the-state", "code-1")
+	if result.Get("error") != "invalid_state" {
+		t.Fatalf("error = %q, want invalid_state", result.Get("error"))
+	}
+	if result.Get("token") != "" {
+		t.Fatal("a token was issued despite the state mismatch")
+	}
+
+	var users int64
+	global.Db.Model(&models.User{}).Count(&users)
+	if users != 0 {
+		t.Fatalf("%d users created, want none", users)
+	}
+}
+
+func TestOIDCCallbackRejectsMissingStateCookie(t *testing.T) {
+	router, provider := setupOIDCTest(t)
+	_, state, nonce := oidcLogin(t, router)
+	provider.expect("code-1", "subject-1", "alice@example.com", true, nonce)
+
+	result := oidcCallback(t, router, nil, state, "code-1")
+	if result.Get("error") != "invalid_state" {
+		t.Fatalf("error = %q, want invalid_state", result.Get("error"))
+	}
+}
+
+func TestOIDCCallbackRejectsUnverifiedEmail(t *testing.T) {
+	router, provider := setupOIDCTest(t)
+	cookie, state, nonce := oidcLogin(t, router)
+	provider.expect("code-1", "subject-1", "alice@example.com", false, nonce)
+
+	result := oidcCallback(t, router, cookie, state, "code-1")
+	if result.Get("error") != errOIDCEmailUnverified.Error() {
+		t.Fatalf("error = %q, want %s", result.Get("error"), errOIDCEmailUnverified)
+	}
+
+	var users, identities int64
+	global.Db.Model(&models.User{}).Count(&users)
+	global.Db.Model(&models.UserIdentity{}).Count(&identities)
+	if users != 0 || identities != 0 {
+		t.Fatalf("created %d users and %d identities, want none", users, identities)
+	}
+}
+
+func TestOIDCCallbackLinksExistingVerifiedAccount(t *testing.T) {
+	router, provider := setupOIDCTest(t)
+
+	email := "alice@example.com"
+	verifiedAt := time.Now()
+	existing := models.User{
+		Username:        "alice",
+		Email:           &email,
+		EmailVerifiedAt: &verifiedAt,
+		Password:        "not-a-real-hash",
+		Role:            models.RoleAuthor,
+		DisplayName:     "Alice",
+	}
+	if err := global.Db.Create(&existing).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	cookie, state, nonce := oidcLogin(t, router)
+	provider.expect("code-1", "subject-1", email, true, nonce)
+
+	result := oidcCallback(t, router, cookie, state, "code-1")
+	if result.Get("error") != "" {
+		t.Fatalf("error = %q", result.Get("error"))
+	}
+	if result.Get("token") == "" || result.Get("refreshToken") == "" {
+		t.Fatalf("no tokens in %v", result)
+	}
+	if result.Get("redirect") != "/dashboard/posts" {
+		t.Fatalf("redirect = %q", result.Get("redirect"))
+	}
+
+	var users int64
+	global.Db.Model(&models.User{}).Count(&users)
+	if users != 1 {
+		t.Fatalf("%d users, want only the existing one", users)
+	}
+	var identity models.UserIdentity
+	if err := global.Db.Where("provider = ? AND subject = ?", testOIDCProvider, "subject-1").First(&identity).Error; err != nil {
+		t.Fatalf("identity not linked: %v", err)
+	}
+	if identity.UserID != existing.ID {
+		t.Fatalf("identity linked to user %d, want %d", identity.UserID, existing.ID)
+	}
+
+	// The next sign-in finds the account through the link, even after the
+	// provider reports a different address.
+	cookie, state, nonce = oidcLogin(t, router)
+	provider.expect("code-2", "subject-1", "alice@elsewhere.example", false, nonce)
+	result = oidcCallback(t, router, cookie, state, "code-2")
+	if result.Get("error") != "" || result.Get("token") == "" {
+		t.Fatalf("second sign-in failed: %v", result)
+	}
+}
+
+func TestOIDCCallbackRefusesUnverifiedLocalAccount(t *testing.T) {
+	router, provider := setupOIDCTest(t)
+
+	email := "alice@example.com"
+	if err := global.Db.Create(&models.User{
+		Username:    "alice",
+		Email:       &email,
+		Password:    "not-a-real-hash",
+		Role:        models.RoleAuthor,
+		DisplayName: "Alice",
+	}).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	cookie, state, nonce := oidcLogin(t, router)
+	provider.expect("code-1", "subject-1", email, true, nonce)
+
+	result := oidcCallback(t, router, cookie, state, "code-1")
+	if result.Get("error") != errOIDCAccountConflict.Error() {
+		t.Fatalf("error = %q, want %s", result.Get("error"), errOIDCAccountConflict)
+	}
+}
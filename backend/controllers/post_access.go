@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gogogo/config"
+	"gogogo/models"
+	"gogogo/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// postUnlockHeader carries the token issued by UnlockPost on later reads of a
+// password-protected post and its comments.
+const postUnlockHeader = "X-Post-Token"
+
+var errPostPasswordRequired = errors.New("password is required for password-protected posts")
+
+type unlockPostRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// postAccess describes what the caller may see of a post.
+type postAccess int
+
+const (
+	postHidden postAccess = iota
+	postLocked
+	postReadable
+)
+
+// resolvePostAccess applies status and visibility for the caller. Authors
+// and editors always get the full post; everyone else needs it published,
+// not private, and unlocked when it has a password.
+func resolvePostAccess(ctx *gin.Context, post models.Post) postAccess {
+	if canManagePost(ctx, post) {
+		return postReadable
+	}
+	if !post.IsPublished() {
+		return postHidden
+	}
+
+	switch post.Visibility {
+	case models.PostVisibilityPrivate:
+		return postHidden
+	case models.PostVisibilityPassword:
+		if hasPostUnlockToken(ctx, post) {
+			return postReadable
+		}
+		return postLocked
+	default:
+		return postReadable
+	}
+}
+
+func hasPostUnlockToken(ctx *gin.Context, post models.Post) bool {
+	token := strings.TrimSpace(ctx.GetHeader(postUnlockHeader))
+	if token == "" {
+		return false
+	}
+
+	claims, err := utils.ValidateActionToken(token, models.TokenPurposePostUnlock)
+	if err != nil {
+		return false
+	}
+	postID, err := claims.SubjectID()
+	return err == nil && postID == post.ID && claims.Id == postUnlockTokenID(post)
+}
+
+// postUnlockTokenID ties unlock tokens to the current password, so changing
+// it locks out every reader who unlocked the old one.
+func postUnlockTokenID(post models.Post) string {
+	return utils.HashToken(post.Password)[:16]
+}
+
+func postUnlockTTL() time.Duration {
+	minutes := config.AppConfig.Posts.UnlockTTLMinutes
+	if minutes <= 0 {
+		minutes = 60
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// UnlockPost checks a password-protected post's password and returns a token
+// to send as X-Post-Token. Wrong guesses are throttled per post and client IP
+// the same way logins are.
+func UnlockPost(ctx *gin.Context) {
+	post, err := loadPostParam(ctx.Param("id"))
+	if err != nil {
+		handlePostLoadError(ctx, err)
+		return
+	}
+
+	if resolvePostAccess(ctx, post) == postHidden {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return
+	}
+	if post.Visibility != models.PostVisibilityPassword {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "post is not password protected"})
+		return
+	}
+
+	var input unlockPostRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	keys := []string{"post:" + strconv.FormatUint(uint64(post.ID), 10) + ":" + ctx.ClientIP()}
+	block, err := checkLoginAllowed(keys)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check attempts"})
+		return
+	}
+	if block != nil {
+		respondLoginBlocked(ctx, block)
+		return
+	}
+
+	if !utils.CheckPassword(input.Password, post.Password) {
+		if err := recordLoginFailure(keys); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record attempt"})
+			return
+		}
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "incorrect password"})
+		return
+	}
+
+	ttl := postUnlockTTL()
+	token, err := utils.GenerateActionToken(models.TokenPurposePostUnlock, post.ID, postUnlockTokenID(post), ttl)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unlock post"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"token":     token,
+		"expiresAt": time.Now().Add(ttl),
+	})
+}
+
+// buildAccessiblePostDTO hides the body of a locked post.
+func buildAccessiblePostDTO(post models.Post, access postAccess, includeContent bool) PostDTO {
+	if access == postLocked {
+		dto := buildPostDTO(post, false)
+		dto.Locked = true
+		return dto
+	}
+	return buildPostDTO(post, includeContent)
+}
+
+// resolvePostPassword works out the stored password hash. A password is
+// required when switching a post to password visibility, may be replaced
+// later, and is dropped for every other visibility.
+func resolvePostPassword(visibility string, password *string, current string) (string, error) {
+	if visibility != models.PostVisibilityPassword {
+		return "", nil
+	}
+	if password == nil || *password == "" {
+		if current == "" {
+			return "", errPostPasswordRequired
+		}
+		return current, nil
+	}
+	return utils.HashPassword(*password)
+}
+
+func sanitizeVisibility(visibility string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(visibility)) {
+	case "", models.PostVisibilityPublic:
+		return models.PostVisibilityPublic, true
+	case models.PostVisibilityUnlisted:
+		return models.PostVisibilityUnlisted, true
+	case models.PostVisibilityPrivate:
+		return models.PostVisibilityPrivate, true
+	case models.PostVisibilityPassword:
+		return models.PostVisibilityPassword, true
+	default:
+		return "", false
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -21,6 +22,8 @@ type postRequest struct {
 	Summary      string     `json:"summary"`
 	Content      string     `json:"content" binding:"required"`
 	Status       string     `json:"status"`
+	Visibility   string     `json:"visibility"`
+	Password     *string    `json:"password"`
 	Slug         string     `json:"slug"`
 	CoverImage   string     `json:"coverImage"`
 	CategoryID   *uint      `json:"categoryId"`
@@ -34,6 +37,8 @@ type updatePostRequest struct {
 	Summary      *string    `json:"summary"`
 	Content      *string    `json:"content"`
 	Status       *string    `json:"status"`
+	Visibility   *string    `json:"visibility"`
+	Password     *string    `json:"password"`
 	Slug         *string    `json:"slug"`
 	CoverImage   *string    `json:"coverImage"`
 	CategoryID   *uint      `json:"categoryId"`
@@ -43,7 +48,7 @@ type updatePostRequest struct {
 }
 
 func ListPosts(ctx *gin.Context) {
-	listPostsWithScopes(ctx, models.PostStatusPublished)
+	listPostsWithScopes(ctx)
 }
 
 func GetPostByID(ctx *gin.Context) {
@@ -60,14 +65,17 @@ func GetPostByID(ctx *gin.Context) {
 		return
 	}
 
-	if post.Status != models.PostStatusPublished {
-		if userID, ok := optionalUserID(ctx); !ok || userID != post.AuthorID {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
-			return
-		}
+	access := resolvePostAccess(ctx, post)
+	if access == postHidden {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"data": buildPostDTO(post, true)})
+	dto := buildAccessiblePostDTO(post, access, true)
+	if !respondCommentCounts(ctx, []*PostDTO{&dto}) {
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": dto})
 }
 
 func GetPostBySlug(ctx *gin.Context) {
@@ -82,22 +90,50 @@ func GetPostBySlug(ctx *gin.Context) {
 		Preload("Author").
 		Preload("Category").
 		Preload("Tags").
-		Preload("Comments", "approved = ?", true).
-		Preload("Comments.User").
 		Where("slug = ?", slug).
 		First(&post).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			redirectOldPostSlug(ctx, slug)
+			return
+		}
 		handlePostLoadError(ctx, err)
 		return
 	}
 
-	if post.Status != models.PostStatusPublished {
-		if userID, ok := optionalUserID(ctx); !ok || userID != post.AuthorID {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
-			return
-		}
+	access := resolvePostAccess(ctx, post)
+	if access == postHidden {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"data": buildPostDTO(post, true)})
+	dto := buildAccessiblePostDTO(post, access, true)
+	if !respondCommentCounts(ctx, []*PostDTO{&dto}) {
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": dto})
+}
+
+// redirectOldPostSlug points a renamed post's old slug at its current one,
+// unless the caller could not see the post anyway.
+func redirectOldPostSlug(ctx *gin.Context, slug string) {
+	id, err := findSlugRedirect(models.SlugEntityPost, slug)
+	if err != nil {
+		handlePostLoadError(ctx, err)
+		return
+	}
+
+	var post models.Post
+	if err := global.Db.First(&post, id).Error; err != nil {
+		handlePostLoadError(ctx, err)
+		return
+	}
+
+	if resolvePostAccess(ctx, post) == postHidden {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return
+	}
+
+	respondSlugMoved(ctx, post.Slug, postSlugPath(post.Slug))
 }
 
 func CreatePost(ctx *gin.Context) {
@@ -123,13 +159,39 @@ func CreatePost(ctx *gin.Context) {
 		return
 	}
 
-	status := sanitizeStatus(input.Status)
+	requestedStatus := sanitizeStatus(input.Status)
+	if err := checkStatusTransition(ctx, models.PostStatusDraft, requestedStatus); err != nil {
+		respondTransitionError(ctx, err)
+		return
+	}
+
+	status, publishedAt, err := resolvePublication(requestedStatus, input.PublishedAt, nil, time.Now())
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	visibility, ok := sanitizeVisibility(input.Visibility)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid visibility"})
+		return
+	}
+
+	password, err := resolvePostPassword(visibility, input.Password, "")
+	if err != nil {
+		if errors.Is(err, errPostPasswordRequired) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
+		return
+	}
+
 	slug := input.Slug
 	if slug == "" {
 		slug = utils.Slugify(input.Title)
 	}
 
-	var err error
 	if slug, err = ensureUniquePostSlug(slug, 0); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate slug"})
 		return
@@ -155,30 +217,38 @@ func CreatePost(ctx *gin.Context) {
 	}
 
 	post := models.Post{
-		Title:      input.Title,
-		Summary:    input.Summary,
-		Content:    input.Content,
-		Status:     status,
-		Slug:       slug,
-		CoverImage: input.CoverImage,
-		AuthorID:   userID,
-		Tags:       tags,
+		Title:       input.Title,
+		Summary:     input.Summary,
+		Content:     input.Content,
+		Status:      status,
+		Visibility:  visibility,
+		Password:    password,
+		Slug:        slug,
+		CoverImage:  input.CoverImage,
+		PublishedAt: publishedAt,
+		AuthorID:    userID,
+		Tags:        tags,
 	}
 
 	if category != nil {
 		post.CategoryID = &category.ID
 	}
 
-	if status == models.PostStatusPublished {
-		if input.PublishedAt != nil {
-			post.PublishedAt = input.PublishedAt
-		} else {
-			now := time.Now()
-			post.PublishedAt = &now
-		}
+	if err := post.RenderContent(); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render content"})
+		return
 	}
 
-	if err := global.Db.Create(&post).Error; err != nil {
+	err = global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&post).Error; err != nil {
+			return err
+		}
+		if err := models.RecordTransition(tx, post.ID, &userID, "", post.Status, ""); err != nil {
+			return err
+		}
+		return recordPostRevision(tx, post.ID, userID, nil)
+	})
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create post"})
 		return
 	}
@@ -188,6 +258,7 @@ func CreatePost(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load post"})
 		return
 	}
+	indexPost(post)
 
 	ctx.JSON(http.StatusCreated, gin.H{"data": buildPostDTO(post, true)})
 }
@@ -199,8 +270,7 @@ func UpdatePost(ctx *gin.Context) {
 		return
 	}
 
-	userID, ok := currentUserID(ctx)
-	if !ok || post.AuthorID != userID {
+	if !canManagePost(ctx, post) {
 		ctx.JSON(http.StatusForbidden, gin.H{"error": "not allowed to edit this post"})
 		return
 	}
@@ -211,15 +281,26 @@ func UpdatePost(ctx *gin.Context) {
 		return
 	}
 
+	previousSlug := post.Slug
+	previousStatus := post.Status
+	requestedStatus := previousStatus
+	// reviewedChange tracks edits to what readers get to see: the text, the
+	// cover, who may read the post and where it is listed. Slug changes are
+	// left out since old slugs keep redirecting, and so are password changes,
+	// which keep the visibility that was reviewed.
+	reviewedChange := false
+
 	if input.Title != nil {
 		if strings.TrimSpace(*input.Title) == "" {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": "title cannot be empty"})
 			return
 		}
+		reviewedChange = reviewedChange || post.Title != *input.Title
 		post.Title = *input.Title
 	}
 
 	if input.Summary != nil {
+		reviewedChange = reviewedChange || post.Summary != *input.Summary
 		post.Summary = *input.Summary
 	}
 
@@ -228,28 +309,54 @@ func UpdatePost(ctx *gin.Context) {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": "content cannot be empty"})
 			return
 		}
+		reviewedChange = reviewedChange || post.Content != *input.Content
 		post.Content = *input.Content
+		if err := post.RenderContent(); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render content"})
+			return
+		}
 	}
 
-	if input.Status != nil {
-		status := sanitizeStatus(*input.Status)
-		post.Status = status
-		if status == models.PostStatusPublished {
-			if input.PublishedAt != nil {
-				publishAt := *input.PublishedAt
-				post.PublishedAt = &publishAt
-			} else if post.PublishedAt == nil {
-				now := time.Now()
-				post.PublishedAt = &now
-			}
-		} else if status == models.PostStatusDraft {
-			post.PublishedAt = nil
+	if input.Status != nil || input.PublishedAt != nil {
+		status := post.Status
+		if input.Status != nil {
+			status = sanitizeStatus(*input.Status)
+		}
+		requestedStatus = status
+		if err := checkStatusTransition(ctx, post.Status, status); err != nil {
+			respondTransitionError(ctx, err)
+			return
 		}
+		status, publishedAt, pubErr := resolvePublication(status, input.PublishedAt, post.PublishedAt, time.Now())
+		if pubErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": pubErr.Error()})
+			return
+		}
+		post.Status = status
+		post.PublishedAt = publishedAt
 	}
 
-	if input.PublishedAt != nil && post.Status == models.PostStatusPublished {
-		publishAt := *input.PublishedAt
-		post.PublishedAt = &publishAt
+	if input.Visibility != nil || input.Password != nil {
+		visibility := post.Visibility
+		if input.Visibility != nil {
+			var ok bool
+			if visibility, ok = sanitizeVisibility(*input.Visibility); !ok {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid visibility"})
+				return
+			}
+		}
+		password, pwErr := resolvePostPassword(visibility, input.Password, post.Password)
+		if pwErr != nil {
+			if errors.Is(pwErr, errPostPasswordRequired) {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": pwErr.Error()})
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
+			return
+		}
+		reviewedChange = reviewedChange || post.Visibility != visibility
+		post.Visibility = visibility
+		post.Password = password
 	}
 
 	if input.Slug != nil {
@@ -265,9 +372,12 @@ func UpdatePost(ctx *gin.Context) {
 	}
 
 	if input.CoverImage != nil {
+		reviewedChange = reviewedChange || post.CoverImage != *input.CoverImage
 		post.CoverImage = *input.CoverImage
 	}
 
+	previousCategoryID := post.CategoryID
+
 	if input.CategoryID != nil {
 		if *input.CategoryID == 0 {
 			post.CategoryID = nil
@@ -301,20 +411,54 @@ func UpdatePost(ctx *gin.Context) {
 		}
 	}
 
+	reviewedChange = reviewedChange || !sameCategory(previousCategoryID, post.CategoryID)
+
+	var tags []models.Tag
 	if input.Tags != nil {
-		tags, tagErr := findOrCreateTags(*input.Tags)
-		if tagErr != nil {
+		var tagErr error
+		if tags, tagErr = findOrCreateTags(*input.Tags); tagErr != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update tags"})
 			return
 		}
-		if err := global.Db.Model(&post).Association("Tags").Replace(tags); err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store tags"})
-			return
-		}
-		post.Tags = tags
+		reviewedChange = reviewedChange || !sameTags(post.Tags, tags)
 	}
 
-	if err := global.Db.Save(&post).Error; err != nil {
+	// Such edits by someone without publish rights to a post that has
+	// already been approved or gone live have to be reviewed again.
+	needsReview := reviewedChange && editNeedsReview(ctx, previousStatus)
+	if needsReview && requestedStatus != previousStatus && isLiveStatus(requestedStatus) {
+		respondTransitionError(ctx, errPublishNotAllowed)
+		return
+	}
+
+	transitionNotes := ""
+	if needsReview && (post.Status == models.PostStatusApproved || isLiveStatus(post.Status)) {
+		post.Status = models.PostStatusPendingReview
+		transitionNotes = "edited after approval"
+	}
+
+	editorID, _ := currentUserID(ctx)
+	err = global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := ensureBaselineRevision(tx, post); err != nil {
+			return err
+		}
+		if err := tx.Omit("Tags").Save(&post).Error; err != nil {
+			return err
+		}
+		if input.Tags != nil {
+			if err := tx.Model(&post).Association("Tags").Replace(tags); err != nil {
+				return err
+			}
+		}
+		if err := models.RecordTransition(tx, post.ID, &editorID, previousStatus, post.Status, transitionNotes); err != nil {
+			return err
+		}
+		if err := models.RecordSlugChange(tx, models.SlugEntityPost, post.ID, previousSlug, post.Slug); err != nil {
+			return err
+		}
+		return recordPostRevision(tx, post.ID, editorID, nil)
+	})
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update post"})
 		return
 	}
@@ -324,10 +468,12 @@ func UpdatePost(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh post"})
 		return
 	}
+	indexPost(post)
 
 	ctx.JSON(http.StatusOK, gin.H{"data": buildPostDTO(post, true)})
 }
 
+// DeletePost moves a post to the trash; see RestorePost and PurgePost.
 func DeletePost(ctx *gin.Context) {
 	post, err := loadPostParam(ctx.Param("id"))
 	if err != nil {
@@ -335,8 +481,7 @@ func DeletePost(ctx *gin.Context) {
 		return
 	}
 
-	userID, ok := currentUserID(ctx)
-	if !ok || post.AuthorID != userID {
+	if !canManagePost(ctx, post) {
 		ctx.JSON(http.StatusForbidden, gin.H{"error": "not allowed to delete this post"})
 		return
 	}
@@ -345,12 +490,20 @@ func DeletePost(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete post"})
 		return
 	}
+	removePostFromIndex(post.ID)
 
 	ctx.Status(http.StatusNoContent)
 }
 
 func ListPostsByCategory(ctx *gin.Context) {
 	category, err := loadCategoryParam(ctx.Param("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		var moved bool
+		if category, moved, err = findCategoryBySlug(ctx.Param("id")); moved {
+			respondSlugMoved(ctx, category.Slug, "/api/categories/"+url.PathEscape(category.Slug)+"/posts")
+			return
+		}
+	}
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
@@ -360,7 +513,7 @@ func ListPostsByCategory(ctx *gin.Context) {
 		return
 	}
 
-	listPostsWithScopes(ctx, models.PostStatusPublished, func(db *gorm.DB) *gorm.DB {
+	listPostsWithScopes(ctx, func(db *gorm.DB) *gorm.DB {
 		return db.Where("category_id = ?", category.ID)
 	})
 }
@@ -372,8 +525,8 @@ func ListPostsByTag(ctx *gin.Context) {
 		return
 	}
 
-	var tag models.Tag
-	if err := global.Db.Where("slug = ?", slug).First(&tag).Error; err != nil {
+	tag, moved, err := findTagBySlug(slug)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
 			return
@@ -381,52 +534,80 @@ func ListPostsByTag(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load tag"})
 		return
 	}
+	if moved {
+		respondSlugMoved(ctx, tag.Slug, "/api/tags/"+url.PathEscape(tag.Slug)+"/posts")
+		return
+	}
 
-	listPostsWithScopes(ctx, models.PostStatusPublished, func(db *gorm.DB) *gorm.DB {
-		return db.Joins("JOIN post_tags ON post_tags.post_id = posts.id").
-			Where("post_tags.tag_id = ?", tag.ID).
-			Distinct("posts.id")
+	listPostsWithScopes(ctx, func(db *gorm.DB) *gorm.DB {
+		return db.Where("posts.id IN (?)",
+			global.Db.Table("post_tags").Select("post_id").Where("tag_id = ?", tag.ID))
 	})
 }
 
-func listPostsWithScopes(ctx *gin.Context, defaultStatus string, extraScopes ...func(*gorm.DB) *gorm.DB) {
+// listPostsWithScopes serves the public listings, which only ever contain
+// posts that are published by now and neither unlisted nor private.
+func listPostsWithScopes(ctx *gin.Context, extraScopes ...func(*gorm.DB) *gorm.DB) {
+	if redirectStaleListFilters(ctx) {
+		return
+	}
+
 	page, pageSize := utils.GetPagination(ctx)
 	includeContent := ctx.DefaultQuery("includeContent", "false") == "true"
 
-	allScopes := append(extraScopes, postFilters(ctx, defaultStatus))
+	allScopes := append(extraScopes, models.PublishedScope(time.Now()), models.ListedScope, postFilters(ctx))
 
-	countDB := global.Db.Model(&models.Post{})
-	for _, scope := range allScopes {
-		countDB = scope(countDB)
-	}
+	var (
+		posts []models.Post
+		total int64
+	)
+	if query := strings.TrimSpace(ctx.Query("search")); query != "" {
+		// Matches keep the search engine's ranking instead of the list order.
+		hits, err := searchMatches(query, allScopes...)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search posts"})
+			return
+		}
+		total = int64(len(hits))
+		if posts, err = loadSearchPage(hits, page, pageSize); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load posts"})
+			return
+		}
+	} else {
+		countDB := global.Db.Model(&models.Post{})
+		for _, scope := range allScopes {
+			countDB = scope(countDB)
+		}
 
-	var total int64
-	if err := countDB.Count(&total).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count posts"})
-		return
-	}
+		if err := countDB.Count(&total).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count posts"})
+			return
+		}
 
-	queryDB := global.Db.Model(&models.Post{})
-	for _, scope := range allScopes {
-		queryDB = scope(queryDB)
-	}
+		queryDB := global.Db.Model(&models.Post{})
+		for _, scope := range allScopes {
+			queryDB = scope(queryDB)
+		}
 
-	var posts []models.Post
-	if err := queryDB.
-		Preload("Author").
-		Preload("Category").
-		Preload("Tags").
-		Order("published_at DESC, created_at DESC").
-		Offset((page - 1) * pageSize).
-		Limit(pageSize).
-		Find(&posts).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load posts"})
-		return
+		if err := queryDB.
+			Preload("Author").
+			Preload("Category").
+			Preload("Tags").
+			Order("published_at DESC, created_at DESC").
+			Offset((page - 1) * pageSize).
+			Limit(pageSize).
+			Find(&posts).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load posts"})
+			return
+		}
 	}
 
 	response := make([]PostDTO, 0, len(posts))
 	for _, post := range posts {
-		response = append(response, buildPostDTO(post, includeContent))
+		response = append(response, buildAccessiblePostDTO(post, resolvePostAccess(ctx, post), includeContent))
+	}
+	if !respondCommentCounts(ctx, postDTORefs(response)) {
+		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{
@@ -437,40 +618,30 @@ func listPostsWithScopes(ctx *gin.Context, defaultStatus string, extraScopes ...
 	})
 }
 
-func postFilters(ctx *gin.Context, defaultStatus string) func(*gorm.DB) *gorm.DB {
-	status := ctx.DefaultQuery("status", defaultStatus)
+func postFilters(ctx *gin.Context) func(*gorm.DB) *gorm.DB {
 	category := ctx.Query("category")
 	tag := ctx.Query("tag")
 	author := ctx.Query("author")
-	search := ctx.Query("search")
 
 	return func(db *gorm.DB) *gorm.DB {
-		if status != "" && status != "all" {
-			db = db.Where("posts.status = ?", status)
-		}
-
+		// Subqueries rather than joins keep the selected columns and the
+		// ORDER BY unambiguous.
 		if category != "" {
-			db = db.Joins("JOIN categories ON categories.id = posts.category_id").
-				Where("categories.slug = ?", category).
-				Distinct("posts.id")
+			db = db.Where("posts.category_id IN (?)",
+				global.Db.Model(&models.Category{}).Select("id").Where("slug = ?", category))
 		}
 
 		if tag != "" {
-			db = db.Joins("JOIN post_tags ON post_tags.post_id = posts.id").
-				Joins("JOIN tags ON tags.id = post_tags.tag_id").
-				Where("tags.slug = ?", tag).
-				Distinct("posts.id")
+			db = db.Where("posts.id IN (?)",
+				global.Db.Table("post_tags").
+					Select("post_tags.post_id").
+					Joins("JOIN tags ON tags.id = post_tags.tag_id").
+					Where("tags.slug = ? AND tags.deleted_at IS NULL", tag))
 		}
 
 		if author != "" {
-			db = db.Joins("JOIN users ON users.id = posts.author_id").
-				Where("users.username = ?", author).
-				Distinct("posts.id")
-		}
-
-		if search != "" {
-			like := "%" + search + "%"
-			db = db.Where("posts.title LIKE ? OR posts.summary LIKE ?", like, like)
+			db = db.Where("posts.author_id IN (?)",
+				global.Db.Model(&models.User{}).Select("id").Where("username = ?", author))
 		}
 
 		return db
@@ -486,7 +657,9 @@ func findOrCreateTags(values []string) ([]models.Tag, error) {
 		}
 		slug := utils.Slugify(value)
 		var tag models.Tag
-		err := global.Db.Where("slug = ?", slug).First(&tag).Error
+		// Matching on name as well finds tags whose slug predates the
+		// current slug rules.
+		err := global.Db.Where("slug = ? OR name = ?", slug, value).First(&tag).Error
 		if err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
 				tag = models.Tag{Name: value, Slug: slug}
@@ -506,13 +679,56 @@ func sanitizeStatus(status string) string {
 	switch strings.ToLower(status) {
 	case models.PostStatusPublished:
 		return models.PostStatusPublished
+	case models.PostStatusScheduled:
+		return models.PostStatusScheduled
 	case models.PostStatusArchived:
 		return models.PostStatusArchived
+	case models.PostStatusPendingReview:
+		return models.PostStatusPendingReview
+	case models.PostStatusChangesRequested:
+		return models.PostStatusChangesRequested
+	case models.PostStatusApproved:
+		return models.PostStatusApproved
 	default:
 		return models.PostStatusDraft
 	}
 }
 
+// resolvePublication works out the stored status and publish time. Asking to
+// publish with a future date schedules the post instead, and a scheduled post
+// whose date has already passed is simply published. Posts under review keep
+// the date the author asked for so it applies once they are published.
+func resolvePublication(status string, requested *time.Time, current *time.Time, now time.Time) (string, *time.Time, error) {
+	switch status {
+	case models.PostStatusDraft:
+		return status, nil, nil
+	case models.PostStatusArchived:
+		return status, current, nil
+	case models.PostStatusPendingReview, models.PostStatusChangesRequested, models.PostStatusApproved:
+		if requested != nil {
+			return status, requested, nil
+		}
+		return status, current, nil
+	}
+
+	publishAt := requested
+	if publishAt == nil {
+		publishAt = current
+	}
+	if publishAt == nil {
+		if status == models.PostStatusScheduled {
+			return "", nil, errors.New("publishedAt is required to schedule a post")
+		}
+		publishAt = &now
+	}
+
+	at := *publishAt
+	if at.After(now) {
+		return models.PostStatusScheduled, &at, nil
+	}
+	return models.PostStatusPublished, &at, nil
+}
+
 func ensureUniquePostSlug(slug string, excludeID uint) (string, error) {
 	base := utils.Slugify(slug)
 	if base == "" {
@@ -522,7 +738,8 @@ func ensureUniquePostSlug(slug string, excludeID uint) (string, error) {
 	candidate := base
 	var count int64
 	for i := 0; ; i++ {
-		query := global.Db.Model(&models.Post{}).Where("slug = ?", candidate)
+		// Posts in the trash keep their slug so they can be restored as-is.
+		query := global.Db.Unscoped().Model(&models.Post{}).Where("slug = ?", candidate)
 		if excludeID > 0 {
 			query = query.Where("id <> ?", excludeID)
 		}
@@ -560,8 +777,6 @@ func loadPostWithRelations(id uint) (models.Post, error) {
 		Preload("Author").
 		Preload("Category").
 		Preload("Tags").
-		Preload("Comments", "approved = ?", true).
-		Preload("Comments.User").
 		First(&post, id).Error
 	return post, err
 }
@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"gogogo/models"
+)
+
+func TestResolvePublication(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	tests := []struct {
+		name       string
+		status     string
+		requested  *time.Time
+		current    *time.Time
+		wantStatus string
+		wantAt     *time.Time
+		wantErr    bool
+	}{
+		{name: "draft clears the date", status: models.PostStatusDraft, current: &past, wantStatus: models.PostStatusDraft},
+		{name: "archive keeps the date", status: models.PostStatusArchived, requested: &future, current: &past, wantStatus: models.PostStatusArchived, wantAt: &past},
+		{name: "review keeps the requested date", status: models.PostStatusPendingReview, requested: &future, current: &past, wantStatus: models.PostStatusPendingReview, wantAt: &future},
+		{name: "approved keeps the current date", status: models.PostStatusApproved, current: &future, wantStatus: models.PostStatusApproved, wantAt: &future},
+		{name: "publish now", status: models.PostStatusPublished, wantStatus: models.PostStatusPublished, wantAt: &now},
+		{name: "publish keeps the original date", status: models.PostStatusPublished, current: &past, wantStatus: models.PostStatusPublished, wantAt: &past},
+		{name: "publish in the future schedules", status: models.PostStatusPublished, requested: &future, wantStatus: models.PostStatusScheduled, wantAt: &future},
+		{name: "schedule in the past publishes", status: models.PostStatusScheduled, requested: &past, wantStatus: models.PostStatusPublished, wantAt: &past},
+		{name: "schedule falls back to the current date", status: models.PostStatusScheduled, current: &future, wantStatus: models.PostStatusScheduled, wantAt: &future},
+		{name: "schedule needs a date", status: models.PostStatusScheduled, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, at, err := resolvePublication(tt.status, tt.requested, tt.current, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %s at %v, want an error", status, at)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolvePublication: %v", err)
+			}
+			if status != tt.wantStatus {
+				t.Fatalf("status %s, want %s", status, tt.wantStatus)
+			}
+			if (at == nil) != (tt.wantAt == nil) || (at != nil && !at.Equal(*tt.wantAt)) {
+				t.Fatalf("publishedAt %v, want %v", at, tt.wantAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gogogo/config"
+	"gogogo/global"
+	"gogogo/models"
+	"gogogo/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type createPreviewRequest struct {
+	Label          string `json:"label"`
+	ExpiresInHours int    `json:"expiresInHours"`
+}
+
+type PostPreviewDTO struct {
+	ID           uint       `json:"id"`
+	Label        string     `json:"label"`
+	CreatedBy    UserDTO    `json:"createdBy"`
+	ExpiresAt    time.Time  `json:"expiresAt"`
+	RevokedAt    *time.Time `json:"revokedAt,omitempty"`
+	Active       bool       `json:"active"`
+	ViewCount    int        `json:"viewCount"`
+	LastViewedAt *time.Time `json:"lastViewedAt,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+}
+
+type PostPreviewViewDTO struct {
+	ID        uint      `json:"id"`
+	IPAddress string    `json:"ipAddress"`
+	UserAgent string    `json:"userAgent"`
+	ViewedAt  time.Time `json:"viewedAt"`
+}
+
+func ListPostPreviews(ctx *gin.Context) {
+	post, ok := loadManagedPost(ctx)
+	if !ok {
+		return
+	}
+
+	var previews []models.PostPreview
+	if err := global.Db.Preload("CreatedBy").
+		Where("post_id = ?", post.ID).
+		Order("created_at DESC").
+		Find(&previews).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load previews"})
+		return
+	}
+
+	now := time.Now()
+	response := make([]PostPreviewDTO, 0, len(previews))
+	for _, preview := range previews {
+		response = append(response, buildPostPreviewDTO(preview, now))
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": response})
+}
+
+// CreatePostPreview returns the preview link exactly once. Anyone holding it
+// can read the post in its current state until it expires or is revoked.
+func CreatePostPreview(ctx *gin.Context) {
+	post, ok := loadManagedPost(ctx)
+	if !ok {
+		return
+	}
+
+	var input createPreviewRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	label := strings.TrimSpace(input.Label)
+	if len(label) > 100 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "label must be at most 100 characters"})
+		return
+	}
+
+	ttl, err := previewTTL(input.ExpiresInHours)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokenID, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+		return
+	}
+
+	token, err := utils.GenerateActionToken(models.TokenPurposePostPreview, post.ID, tokenID, ttl)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+		return
+	}
+
+	userID, _ := currentUserID(ctx)
+	preview := models.PostPreview{
+		PostID:      post.ID,
+		CreatedByID: userID,
+		Label:       label,
+		TokenID:     tokenID,
+		ExpiresAt:   time.Now().Add(ttl),
+	}
+	if err := global.Db.Create(&preview).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create preview"})
+		return
+	}
+
+	if err := global.Db.Preload("CreatedBy").First(&preview, preview.ID).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load preview"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"data":  buildPostPreviewDTO(preview, time.Now()),
+		"token": token,
+		"url":   frontendLink("/preview", token),
+	})
+}
+
+// RevokePostPreview stops a preview link from working. The record and its
+// view log are kept.
+func RevokePostPreview(ctx *gin.Context) {
+	post, ok := loadManagedPost(ctx)
+	if !ok {
+		return
+	}
+
+	preview, err := loadPostPreview(post.ID, ctx.Param("previewId"))
+	if err != nil {
+		handlePreviewLoadError(ctx, err)
+		return
+	}
+
+	if preview.RevokedAt == nil {
+		now := time.Now()
+		if err := global.Db.Model(&preview).Update("revoked_at", now).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke preview"})
+			return
+		}
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func ListPostPreviewViews(ctx *gin.Context) {
+	post, ok := loadManagedPost(ctx)
+	if !ok {
+		return
+	}
+
+	preview, err := loadPostPreview(post.ID, ctx.Param("previewId"))
+	if err != nil {
+		handlePreviewLoadError(ctx, err)
+		return
+	}
+
+	page, pageSize := utils.GetPagination(ctx)
+
+	var total int64
+	if err := global.Db.Model(&models.PostPreviewView{}).Where("preview_id = ?", preview.ID).Count(&total).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count views"})
+		return
+	}
+
+	var views []models.PostPreviewView
+	if err := global.Db.Where("preview_id = ?", preview.ID).
+		Order("created_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&views).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load views"})
+		return
+	}
+
+	response := make([]PostPreviewViewDTO, 0, len(views))
+	for _, view := range views {
+		response = append(response, PostPreviewViewDTO{
+			ID:        view.ID,
+			IPAddress: view.IPAddress,
+			UserAgent: view.UserAgent,
+			ViewedAt:  view.CreatedAt,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":     response,
+		"page":     page,
+		"pageSize": pageSize,
+		"total":    total,
+	})
+}
+
+// GetPostPreview renders a post for whoever holds a valid preview link,
+// whatever its status or visibility, and logs the read.
+func GetPostPreview(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("X-Robots-Tag", "noindex")
+
+	claims, err := utils.ValidateActionToken(ctx.Query("token"), models.TokenPurposePostPreview)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "preview not found or expired"})
+		return
+	}
+	postID, err := claims.SubjectID()
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "preview not found or expired"})
+		return
+	}
+
+	var preview models.PostPreview
+	if err := global.Db.Where("token_id = ? AND post_id = ?", claims.Id, postID).First(&preview).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "preview not found or expired"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load preview"})
+		return
+	}
+
+	now := time.Now()
+	if !preview.IsActive(now) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "preview not found or expired"})
+		return
+	}
+
+	post, err := loadPostWithRelations(preview.PostID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "preview not found or expired"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load post"})
+		return
+	}
+
+	err = global.Db.Transaction(func(tx *gorm.DB) error {
+		view := models.PostPreviewView{
+			PreviewID: preview.ID,
+			PostID:    post.ID,
+			IPAddress: ctx.ClientIP(),
+			UserAgent: truncate(ctx.Request.UserAgent(), 255),
+		}
+		if err := tx.Create(&view).Error; err != nil {
+			return err
+		}
+		return tx.Model(&preview).UpdateColumns(map[string]interface{}{
+			"view_count":     gorm.Expr("view_count + 1"),
+			"last_viewed_at": now,
+		}).Error
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record preview"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":      buildPostDTO(post, true),
+		"expiresAt": preview.ExpiresAt,
+	})
+}
+
+func previewTTL(hours int) (time.Duration, error) {
+	if hours < 0 {
+		return 0, errors.New("expiresInHours cannot be negative")
+	}
+
+	postsConfig := config.AppConfig.Posts
+	if hours == 0 {
+		hours = postsConfig.PreviewTTLHours
+	}
+	if hours <= 0 {
+		hours = 72
+	}
+	if postsConfig.PreviewMaxTTLHours > 0 && hours > postsConfig.PreviewMaxTTLHours {
+		return 0, errors.New("expiresInHours cannot exceed " + strconv.Itoa(postsConfig.PreviewMaxTTLHours))
+	}
+	return time.Duration(hours) * time.Hour, nil
+}
+
+func loadPostPreview(postID uint, param string) (models.PostPreview, error) {
+	var preview models.PostPreview
+	id, err := strconv.ParseUint(param, 10, 64)
+	if err != nil {
+		return preview, gorm.ErrRecordNotFound
+	}
+
+	err = global.Db.Preload("CreatedBy").Where("id = ? AND post_id = ?", id, postID).First(&preview).Error
+	return preview, err
+}
+
+func handlePreviewLoadError(ctx *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "preview not found"})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load preview"})
+}
+
+func buildPostPreviewDTO(preview models.PostPreview, now time.Time) PostPreviewDTO {
+	return PostPreviewDTO{
+		ID:           preview.ID,
+		Label:        preview.Label,
+		CreatedBy:    buildPublicUserDTO(preview.CreatedBy),
+		ExpiresAt:    preview.ExpiresAt,
+		RevokedAt:    preview.RevokedAt,
+		Active:       preview.IsActive(now),
+		ViewCount:    preview.ViewCount,
+		LastViewedAt: preview.LastViewedAt,
+		CreatedAt:    preview.CreatedAt,
+	}
+}
@@ -0,0 +1,339 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"gogogo/global"
+	"gogogo/models"
+	"gogogo/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	reviewDecisionApprove        = "approve"
+	reviewDecisionRequestChanges = "request_changes"
+)
+
+var (
+	errPublishNotAllowed = errors.New("not allowed to publish posts; submit the post for review instead")
+	errInvalidTransition = errors.New("cannot change status")
+)
+
+type submitPostRequest struct {
+	Notes string `json:"notes"`
+}
+
+type reviewPostRequest struct {
+	Decision string `json:"decision" binding:"required"`
+	Notes    string `json:"notes"`
+}
+
+type PostTransitionDTO struct {
+	ID        uint      `json:"id"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Notes     string    `json:"notes,omitempty"`
+	Actor     *UserDTO  `json:"actor,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type ReviewQueueItemDTO struct {
+	Post        PostDTO   `json:"post"`
+	SubmittedAt time.Time `json:"submittedAt"`
+	Notes       string    `json:"notes,omitempty"`
+}
+
+// SubmitPostForReview moves a draft, or a post sent back with changes
+// requested, into the review queue.
+func SubmitPostForReview(ctx *gin.Context) {
+	post, ok := loadManagedPost(ctx)
+	if !ok {
+		return
+	}
+
+	var input submitPostRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if post.Status == models.PostStatusPendingReview {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "post is already waiting for review"})
+		return
+	}
+	if err := checkStatusTransition(ctx, post.Status, models.PostStatusPendingReview); err != nil {
+		respondTransitionError(ctx, err)
+		return
+	}
+
+	changePostStatus(ctx, post, models.PostStatusPendingReview, strings.TrimSpace(input.Notes))
+}
+
+// ReviewPost lets an editor approve a post waiting for review or send it
+// back to its author with notes. Editors cannot review their own posts.
+func ReviewPost(ctx *gin.Context) {
+	post, err := loadPostParam(ctx.Param("id"))
+	if err != nil {
+		handlePostLoadError(ctx, err)
+		return
+	}
+
+	var input reviewPostRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := currentUserID(ctx)
+	if post.AuthorID == userID {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "cannot review your own post"})
+		return
+	}
+	if post.Status != models.PostStatusPendingReview {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "post is not waiting for review"})
+		return
+	}
+
+	notes := strings.TrimSpace(input.Notes)
+	var status string
+	switch input.Decision {
+	case reviewDecisionApprove:
+		status = models.PostStatusApproved
+	case reviewDecisionRequestChanges:
+		if notes == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "notes are required when requesting changes"})
+			return
+		}
+		status = models.PostStatusChangesRequested
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "decision must be approve or request_changes"})
+		return
+	}
+
+	changePostStatus(ctx, post, status, notes)
+}
+
+// ListPostTransitions returns a post's status history, newest first.
+func ListPostTransitions(ctx *gin.Context) {
+	post, ok := loadManagedPost(ctx)
+	if !ok {
+		return
+	}
+
+	var transitions []models.PostTransition
+	if err := global.Db.Preload("Actor").
+		Where("post_id = ?", post.ID).
+		Order("created_at DESC, id DESC").
+		Find(&transitions).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load history"})
+		return
+	}
+
+	response := make([]PostTransitionDTO, 0, len(transitions))
+	for _, transition := range transitions {
+		response = append(response, buildPostTransitionDTO(transition))
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": response})
+}
+
+// ListReviewQueue lists posts waiting for an editor, longest waiting first.
+func ListReviewQueue(ctx *gin.Context) {
+	page, pageSize := utils.GetPagination(ctx)
+	pending := global.Db.Model(&models.Post{}).Where("status = ?", models.PostStatusPendingReview)
+
+	var total int64
+	if err := pending.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count posts"})
+		return
+	}
+
+	var posts []models.Post
+	if err := pending.Session(&gorm.Session{}).
+		Preload("Author").
+		Preload("Category").
+		Preload("Tags").
+		Order("updated_at ASC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&posts).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load posts"})
+		return
+	}
+
+	submissions, err := latestSubmissions(posts)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load history"})
+		return
+	}
+
+	response := make([]ReviewQueueItemDTO, 0, len(posts))
+	for _, post := range posts {
+		item := ReviewQueueItemDTO{Post: buildPostDTO(post, false), SubmittedAt: post.UpdatedAt}
+		if submission, ok := submissions[post.ID]; ok {
+			item.SubmittedAt = submission.CreatedAt
+			item.Notes = submission.Notes
+		}
+		response = append(response, item)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":     response,
+		"page":     page,
+		"pageSize": pageSize,
+		"total":    total,
+	})
+}
+
+// latestSubmissions finds the most recent move into review for each post.
+func latestSubmissions(posts []models.Post) (map[uint]models.PostTransition, error) {
+	result := make(map[uint]models.PostTransition, len(posts))
+	if len(posts) == 0 {
+		return result, nil
+	}
+
+	ids := make([]uint, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+
+	var transitions []models.PostTransition
+	if err := global.Db.
+		Where("post_id IN ? AND to_status = ?", ids, models.PostStatusPendingReview).
+		Order("created_at DESC, id DESC").
+		Find(&transitions).Error; err != nil {
+		return nil, err
+	}
+
+	for _, transition := range transitions {
+		if _, seen := result[transition.PostID]; !seen {
+			result[transition.PostID] = transition
+		}
+	}
+	return result, nil
+}
+
+// checkStatusTransition validates a status change requested by the caller.
+// Anyone who can edit a post may move it back to draft, archive it or submit
+// it for review; approving and requesting changes go through ReviewPost.
+// Publishing needs posts.publish unless an editor has approved the post.
+func checkStatusTransition(ctx *gin.Context, from, to string) error {
+	if from == to {
+		return nil
+	}
+
+	switch to {
+	case models.PostStatusDraft, models.PostStatusArchived:
+		return nil
+	case models.PostStatusPendingReview:
+		if from == models.PostStatusDraft || from == models.PostStatusChangesRequested {
+			return nil
+		}
+	case models.PostStatusPublished, models.PostStatusScheduled:
+		switch from {
+		case models.PostStatusApproved, models.PostStatusScheduled, models.PostStatusPublished:
+			return nil
+		}
+		if canPublishPosts(ctx) {
+			return nil
+		}
+		return errPublishNotAllowed
+	}
+
+	return fmt.Errorf("%w from %s to %s", errInvalidTransition, from, to)
+}
+
+// editNeedsReview reports whether a content change to a post in the given
+// status has to go back through review: the post was approved, scheduled or
+// published and the caller cannot publish on their own.
+func editNeedsReview(ctx *gin.Context, status string) bool {
+	return (status == models.PostStatusApproved || isLiveStatus(status)) && !canPublishPosts(ctx)
+}
+
+func sameCategory(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// sameTags reports whether both lists hold the same tags in any order.
+func sameTags(a, b []models.Tag) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	ids := make(map[uint]bool, len(a))
+	for _, tag := range a {
+		ids[tag.ID] = true
+	}
+	for _, tag := range b {
+		if !ids[tag.ID] {
+			return false
+		}
+	}
+	return true
+}
+
+func isLiveStatus(status string) bool {
+	return status == models.PostStatusPublished || status == models.PostStatusScheduled
+}
+
+func canPublishPosts(ctx *gin.Context) bool {
+	return models.RoleHasPermission(currentUserRole(ctx), models.PermissionPublishPosts)
+}
+
+func respondTransitionError(ctx *gin.Context, err error) {
+	if errors.Is(err, errPublishNotAllowed) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// changePostStatus stores a new status with its history entry and responds
+// with the updated post.
+func changePostStatus(ctx *gin.Context, post models.Post, status, notes string) {
+	actorID, _ := currentUserID(ctx)
+	previous := post.Status
+
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&post).Update("status", status).Error; err != nil {
+			return err
+		}
+		return models.RecordTransition(tx, post.ID, &actorID, previous, status, notes)
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update post"})
+		return
+	}
+
+	post, err = loadPostWithRelations(post.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load post"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": buildPostDTO(post, true)})
+}
+
+func buildPostTransitionDTO(transition models.PostTransition) PostTransitionDTO {
+	dto := PostTransitionDTO{
+		ID:        transition.ID,
+		From:      transition.FromStatus,
+		To:        transition.ToStatus,
+		Notes:     transition.Notes,
+		CreatedAt: transition.CreatedAt,
+	}
+	if transition.Actor != nil {
+		actor := buildPublicUserDTO(*transition.Actor)
+		dto.Actor = &actor
+	}
+	return dto
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"gogogo/global"
+	"gogogo/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCheckStatusTransition(t *testing.T) {
+	const (
+		draft     = models.PostStatusDraft
+		pending   = models.PostStatusPendingReview
+		changes   = models.PostStatusChangesRequested
+		approved  = models.PostStatusApproved
+		published = models.PostStatusPublished
+		scheduled = models.PostStatusScheduled
+		archived  = models.PostStatusArchived
+	)
+
+	tests := []struct {
+		role     string
+		from, to string
+		want     error
+	}{
+		{models.RoleContributor, draft, draft, nil},
+		{models.RoleContributor, published, draft, nil},
+		{models.RoleContributor, published, archived, nil},
+		{models.RoleContributor, draft, pending, nil},
+		{models.RoleContributor, changes, pending, nil},
+		{models.RoleContributor, approved, pending, errInvalidTransition},
+		{models.RoleContributor, published, pending, errInvalidTransition},
+		{models.RoleContributor, draft, published, errPublishNotAllowed},
+		{models.RoleContributor, pending, scheduled, errPublishNotAllowed},
+		{models.RoleContributor, approved, published, nil},
+		{models.RoleContributor, approved, scheduled, nil},
+		{models.RoleContributor, scheduled, published, nil},
+		{models.RoleContributor, draft, approved, errInvalidTransition},
+		{models.RoleContributor, pending, changes, errInvalidTransition},
+		{models.RoleAuthor, draft, published, nil},
+		{models.RoleAuthor, archived, scheduled, nil},
+		{models.RoleAuthor, pending, approved, errInvalidTransition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.role+"/"+tt.from+"->"+tt.to, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Set("role", tt.role)
+
+			err := checkStatusTransition(ctx, tt.from, tt.to)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("err = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func setupReviewTest(t *testing.T) (*gin.Engine, models.Post) {
+	t.Helper()
+	setupTestDB(t, allTestModels...)
+
+	contributor := createTestUser(t, "carol", models.RoleContributor)
+	post := createTestPost(t, contributor, "Hello", models.PostStatusPublished)
+	if err := global.Db.Model(&post).Update("visibility", models.PostVisibilityPrivate).Error; err != nil {
+		t.Fatalf("make post private: %v", err)
+	}
+
+	router := gin.New()
+	asUser(router, contributor)
+	router.PUT("/api/posts/:id", UpdatePost)
+	return router, post
+}
+
+func TestUpdatePostSendsVisibilityChangesBackToReview(t *testing.T) {
+	router, post := setupReviewTest(t)
+	path := "/api/posts/" + strconv.FormatUint(uint64(post.ID), 10)
+
+	status, body := serveJSON(t, router, http.MethodPut, path, gin.H{"visibility": models.PostVisibilityPublic})
+	if status != http.StatusOK {
+		t.Fatalf("status %d, body %v", status, body)
+	}
+
+	var stored models.Post
+	global.Db.First(&stored, post.ID)
+	if stored.Status != models.PostStatusPendingReview {
+		t.Fatalf("status %s after making the post public, want pending_review", stored.Status)
+	}
+}
+
+func TestUpdatePostRefusesToRepublishAReviewedChange(t *testing.T) {
+	router, post := setupReviewTest(t)
+	path := "/api/posts/" + strconv.FormatUint(uint64(post.ID), 10)
+
+	// Unpublishing needs no review, but going live again with new tags does.
+	if status, body := serveJSON(t, router, http.MethodPut, path, gin.H{"status": models.PostStatusDraft}); status != http.StatusOK {
+		t.Fatalf("unpublish: status %d, body %v", status, body)
+	}
+	global.Db.Model(&post).Update("status", models.PostStatusApproved)
+
+	status, _ := serveJSON(t, router, http.MethodPut, path, gin.H{"status": models.PostStatusPublished, "tags": []string{"news"}})
+	if status != http.StatusForbidden {
+		t.Fatalf("status %d, want 403", status)
+	}
+
+	// A slug change alone is not reviewed.
+	if status, body := serveJSON(t, router, http.MethodPut, path, gin.H{"status": models.PostStatusPublished, "slug": "hello-again"}); status != http.StatusOK {
+		t.Fatalf("publish with a new slug: status %d, body %v", status, body)
+	}
+}
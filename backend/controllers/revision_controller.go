@@ -0,0 +1,422 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gogogo/config"
+	"gogogo/global"
+	"gogogo/models"
+	"gogogo/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PostRevisionDTO struct {
+	Number       int       `json:"number"`
+	Title        string    `json:"title"`
+	Summary      string    `json:"summary"`
+	Content      string    `json:"content,omitempty"`
+	Slug         string    `json:"slug"`
+	Status       string    `json:"status"`
+	CoverImage   string    `json:"coverImage,omitempty"`
+	CategoryID   *uint     `json:"categoryId,omitempty"`
+	CategoryName string    `json:"categoryName,omitempty"`
+	Tags         []string  `json:"tags"`
+	RestoredFrom *int      `json:"restoredFrom,omitempty"`
+	Editor       UserDTO   `json:"editor"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+type fieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+func ListPostRevisions(ctx *gin.Context) {
+	post, ok := loadManagedPost(ctx)
+	if !ok {
+		return
+	}
+
+	page, pageSize := utils.GetPagination(ctx)
+
+	var total int64
+	if err := global.Db.Model(&models.PostRevision{}).Where("post_id = ?", post.ID).Count(&total).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count revisions"})
+		return
+	}
+
+	var revisions []models.PostRevision
+	if err := global.Db.Preload("Editor").
+		Where("post_id = ?", post.ID).
+		Order("number DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&revisions).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load revisions"})
+		return
+	}
+
+	response := make([]PostRevisionDTO, 0, len(revisions))
+	for _, revision := range revisions {
+		response = append(response, buildPostRevisionDTO(revision, false))
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":     response,
+		"page":     page,
+		"pageSize": pageSize,
+		"total":    total,
+	})
+}
+
+func GetPostRevision(ctx *gin.Context) {
+	post, ok := loadManagedPost(ctx)
+	if !ok {
+		return
+	}
+
+	revision, err := loadPostRevision(post.ID, ctx.Param("rev"))
+	if err != nil {
+		handleRevisionLoadError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": buildPostRevisionDTO(revision, true)})
+}
+
+// DiffPostRevisions compares ?from=<rev> with ?to=<rev>; to defaults to the
+// latest revision. Content is diffed line by line, other fields are reported
+// only when they differ.
+func DiffPostRevisions(ctx *gin.Context) {
+	post, ok := loadManagedPost(ctx)
+	if !ok {
+		return
+	}
+
+	from, err := loadPostRevision(post.ID, ctx.Query("from"))
+	if err != nil {
+		handleRevisionLoadError(ctx, err)
+		return
+	}
+
+	var to models.PostRevision
+	if toParam := ctx.Query("to"); toParam != "" {
+		to, err = loadPostRevision(post.ID, toParam)
+	} else {
+		err = global.Db.Preload("Editor").Where("post_id = ?", post.ID).Order("number DESC").First(&to).Error
+	}
+	if err != nil {
+		handleRevisionLoadError(ctx, err)
+		return
+	}
+
+	changes := make(map[string]fieldChange)
+	addChange := func(field string, before interface{}, after interface{}, changed bool) {
+		if changed {
+			changes[field] = fieldChange{From: before, To: after}
+		}
+	}
+	addChange("title", from.Title, to.Title, from.Title != to.Title)
+	addChange("summary", from.Summary, to.Summary, from.Summary != to.Summary)
+	addChange("slug", from.Slug, to.Slug, from.Slug != to.Slug)
+	addChange("status", from.Status, to.Status, from.Status != to.Status)
+	addChange("coverImage", from.CoverImage, to.CoverImage, from.CoverImage != to.CoverImage)
+	addChange("category", from.CategoryName, to.CategoryName, from.CategoryName != to.CategoryName)
+	addChange("tags", revisionTags(from), revisionTags(to),
+		strings.Join(from.TagNames, "\n") != strings.Join(to.TagNames, "\n"))
+
+	lines, err := utils.DiffLines(from.Content, to.Content)
+	if err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	added, removed := 0, 0
+	for _, line := range lines {
+		switch line.Op {
+		case utils.DiffInsert:
+			added++
+		case utils.DiffDelete:
+			removed++
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"from":    buildPostRevisionDTO(from, false),
+		"to":      buildPostRevisionDTO(to, false),
+		"changes": changes,
+		"content": lines,
+		"stats":   gin.H{"added": added, "removed": removed},
+	})
+}
+
+// RestorePostRevision copies a revision's content back onto the post and
+// records the result as a new revision. Status and publish date are left
+// alone: restoring text should not publish or unpublish anything. The one
+// exception is an approved post, which goes back for review when restored by
+// someone who could not have published it directly.
+func RestorePostRevision(ctx *gin.Context) {
+	post, ok := loadManagedPost(ctx)
+	if !ok {
+		return
+	}
+
+	revision, err := loadPostRevision(post.ID, ctx.Param("rev"))
+	if err != nil {
+		handleRevisionLoadError(ctx, err)
+		return
+	}
+
+	editorID, _ := currentUserID(ctx)
+	tags, err := findOrCreateTags(revision.TagNames)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore tags"})
+		return
+	}
+
+	slug, err := ensureUniquePostSlug(revision.Slug, post.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore slug"})
+		return
+	}
+
+	// A category deleted since the revision was taken cannot come back.
+	categoryID := revision.CategoryID
+	if categoryID != nil {
+		if _, err := resolveCategory(categoryID, ""); err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load category"})
+				return
+			}
+			categoryID = nil
+		}
+	}
+
+	previousSlug := post.Slug
+	post.Title = revision.Title
+	post.Summary = revision.Summary
+	post.Content = revision.Content
+	post.Slug = slug
+	post.CoverImage = revision.CoverImage
+	post.CategoryID = categoryID
+	if err := post.RenderContent(); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render content"})
+		return
+	}
+
+	restoredFrom := revision.Number
+	err = global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := ensureBaselineRevision(tx, post); err != nil {
+			return err
+		}
+		// Select makes the update write zero values such as an empty summary.
+		if err := tx.Model(&post).
+			Select("title", "summary", "content", "content_html", "toc", "slug", "cover_image", "category_id").
+			Updates(&post).Error; err != nil {
+			return err
+		}
+		if err := models.RecordSlugChange(tx, models.SlugEntityPost, post.ID, previousSlug, post.Slug); err != nil {
+			return err
+		}
+		if editNeedsReview(ctx, post.Status) {
+			previousStatus := post.Status
+			if err := tx.Model(&post).Update("status", models.PostStatusPendingReview).Error; err != nil {
+				return err
+			}
+			notes := fmt.Sprintf("restored revision %d after approval", revision.Number)
+			if err := models.RecordTransition(tx, post.ID, &editorID, previousStatus, models.PostStatusPendingReview, notes); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&post).Association("Tags").Replace(tags); err != nil {
+			return err
+		}
+		return recordPostRevision(tx, post.ID, editorID, &restoredFrom)
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore revision"})
+		return
+	}
+
+	post, err = loadPostWithRelations(post.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh post"})
+		return
+	}
+	indexPost(post)
+
+	ctx.JSON(http.StatusOK, gin.H{"data": buildPostDTO(post, true)})
+}
+
+// recordPostRevision snapshots the post as stored in tx, then applies the
+// retention policy. Call it inside the transaction that saved the post, so
+// an edit is never kept without its revision. The post row stays locked
+// until tx ends, which keeps concurrent edits from taking the same number.
+func recordPostRevision(tx *gorm.DB, postID, editorID uint, restoredFrom *int) error {
+	post, err := lockPostForRevision(tx, postID)
+	if err != nil {
+		return err
+	}
+
+	tagNames := make([]string, 0, len(post.Tags))
+	for _, tag := range post.Tags {
+		tagNames = append(tagNames, tag.Name)
+	}
+
+	revision := models.PostRevision{
+		PostID:       post.ID,
+		EditorID:     editorID,
+		Title:        post.Title,
+		Summary:      post.Summary,
+		Content:      post.Content,
+		Slug:         post.Slug,
+		Status:       post.Status,
+		CoverImage:   post.CoverImage,
+		CategoryID:   post.CategoryID,
+		TagNames:     tagNames,
+		RestoredFrom: restoredFrom,
+	}
+	if post.Category != nil {
+		revision.CategoryName = post.Category.Name
+	}
+
+	var latest int
+	if err := tx.Model(&models.PostRevision{}).Unscoped().
+		Where("post_id = ?", post.ID).
+		Select("COALESCE(MAX(number), 0)").
+		Scan(&latest).Error; err != nil {
+		return err
+	}
+
+	revision.Number = latest + 1
+	if err := tx.Create(&revision).Error; err != nil {
+		return err
+	}
+	return prunePostRevisions(tx, post.ID, revision.Number)
+}
+
+// lockPostForRevision loads the post with Tags and Category and locks its row
+// for the rest of tx.
+func lockPostForRevision(tx *gorm.DB, postID uint) (models.Post, error) {
+	var post models.Post
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Category").
+		Preload("Tags").
+		First(&post, postID).Error
+	return post, err
+}
+
+// ensureBaselineRevision snapshots posts written before revisions existed so
+// their original text survives the first edit. Call it in the edit's
+// transaction before the post is changed.
+func ensureBaselineRevision(tx *gorm.DB, post models.Post) error {
+	if _, err := lockPostForRevision(tx, post.ID); err != nil {
+		return err
+	}
+
+	var count int64
+	if err := tx.Model(&models.PostRevision{}).Where("post_id = ?", post.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return recordPostRevision(tx, post.ID, post.AuthorID, nil)
+}
+
+// prunePostRevisions keeps the newest revision_limit revisions and drops those
+// older than revision_max_age_days. The latest revision is never removed.
+func prunePostRevisions(tx *gorm.DB, postID uint, latest int) error {
+	postsConfig := config.AppConfig.Posts
+
+	if limit := postsConfig.RevisionLimit; limit > 0 && latest > limit {
+		if err := tx.Unscoped().
+			Where("post_id = ? AND number <= ?", postID, latest-limit).
+			Delete(&models.PostRevision{}).Error; err != nil {
+			return err
+		}
+	}
+
+	if days := postsConfig.RevisionMaxAgeDays; days > 0 {
+		cutoff := time.Now().AddDate(0, 0, -days)
+		if err := tx.Unscoped().
+			Where("post_id = ? AND number < ? AND created_at < ?", postID, latest, cutoff).
+			Delete(&models.PostRevision{}).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadManagedPost loads the :id post and checks that the caller may edit it,
+// writing the error response otherwise.
+func loadManagedPost(ctx *gin.Context) (models.Post, bool) {
+	post, err := loadPostParam(ctx.Param("id"))
+	if err != nil {
+		handlePostLoadError(ctx, err)
+		return post, false
+	}
+
+	if !canManagePost(ctx, post) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "not allowed to edit this post"})
+		return post, false
+	}
+	return post, true
+}
+
+func loadPostRevision(postID uint, param string) (models.PostRevision, error) {
+	var revision models.PostRevision
+	number, err := strconv.Atoi(param)
+	if err != nil || number < 1 {
+		return revision, gorm.ErrRecordNotFound
+	}
+
+	err = global.Db.Preload("Editor").
+		Where("post_id = ? AND number = ?", postID, number).
+		First(&revision).Error
+	return revision, err
+}
+
+func handleRevisionLoadError(ctx *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "revision not found"})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load revision"})
+}
+
+func buildPostRevisionDTO(revision models.PostRevision, includeContent bool) PostRevisionDTO {
+	dto := PostRevisionDTO{
+		Number:       revision.Number,
+		Title:        revision.Title,
+		Summary:      revision.Summary,
+		Slug:         revision.Slug,
+		Status:       revision.Status,
+		CoverImage:   revision.CoverImage,
+		CategoryID:   revision.CategoryID,
+		CategoryName: revision.CategoryName,
+		Tags:         revisionTags(revision),
+		RestoredFrom: revision.RestoredFrom,
+		Editor:       buildPublicUserDTO(revision.Editor),
+		CreatedAt:    revision.CreatedAt,
+	}
+	if includeContent {
+		dto.Content = revision.Content
+	}
+	return dto
+}
+
+func revisionTags(revision models.PostRevision) []string {
+	if revision.TagNames == nil {
+		return []string{}
+	}
+	return revision.TagNames
+}
@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"gogogo/config"
+	"gogogo/global"
+	"gogogo/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func revisionNumbers(t *testing.T, postID uint) []int {
+	t.Helper()
+	var numbers []int
+	if err := global.Db.Model(&models.PostRevision{}).
+		Where("post_id = ?", postID).
+		Order("number").
+		Pluck("number", &numbers).Error; err != nil {
+		t.Fatalf("load revisions: %v", err)
+	}
+	return numbers
+}
+
+func recordTestRevision(t *testing.T, post models.Post) {
+	t.Helper()
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		return recordPostRevision(tx, post.ID, post.AuthorID, nil)
+	})
+	if err != nil {
+		t.Fatalf("record revision: %v", err)
+	}
+}
+
+func TestPostRevisionsKeepTheNewestRevisionLimit(t *testing.T) {
+	setupTestDB(t, allTestModels...)
+	config.AppConfig.Posts.RevisionLimit = 3
+	post := createTestPost(t, createTestUser(t, "alice", models.RoleAuthor), "Hello", models.PostStatusDraft)
+
+	for i := 0; i < 5; i++ {
+		recordTestRevision(t, post)
+	}
+
+	got := revisionNumbers(t, post.ID)
+	if len(got) != 3 || got[0] != 3 || got[2] != 5 {
+		t.Fatalf("revisions %v, want [3 4 5]", got)
+	}
+}
+
+func TestPostRevisionsDropOldOnesButKeepTheLatest(t *testing.T) {
+	setupTestDB(t, allTestModels...)
+	config.AppConfig.Posts.RevisionMaxAgeDays = 30
+	post := createTestPost(t, createTestUser(t, "alice", models.RoleAuthor), "Hello", models.PostStatusDraft)
+
+	recordTestRevision(t, post)
+	recordTestRevision(t, post)
+	old := time.Now().AddDate(0, 0, -31)
+	global.Db.Model(&models.PostRevision{}).Where("post_id = ?", post.ID).Update("created_at", old)
+
+	// Pruning never removes the newest revision, however old it is.
+	if err := prunePostRevisions(global.Db, post.ID, 2); err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	if got := revisionNumbers(t, post.ID); len(got) != 1 || got[0] != 2 {
+		t.Fatalf("revisions %v, want [2]", got)
+	}
+
+	recordTestRevision(t, post)
+	if got := revisionNumbers(t, post.ID); len(got) != 1 || got[0] != 3 {
+		t.Fatalf("revisions %v, want [3]", got)
+	}
+}
+
+func TestUpdatePostRollsBackWhenTheRevisionFails(t *testing.T) {
+	setupTestDB(t, allTestModels...)
+	author := createTestUser(t, "alice", models.RoleAuthor)
+	post := createTestPost(t, author, "Hello", models.PostStatusDraft)
+	recordTestRevision(t, post)
+
+	router := gin.New()
+	asUser(router, author)
+	router.PUT("/api/posts/:id", UpdatePost)
+
+	err := global.Db.Callback().Create().Before("gorm:create").Register("test:fail_revisions", func(db *gorm.DB) {
+		if db.Statement.Table == "post_revisions" {
+			_ = db.AddError(errors.New("revision storage unavailable"))
+		}
+	})
+	if err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+
+	status, _ := serveJSON(t, router, http.MethodPut, "/api/posts/"+strconv.FormatUint(uint64(post.ID), 10), gin.H{"title": "Changed"})
+	if status != http.StatusInternalServerError {
+		t.Fatalf("status %d, want 500", status)
+	}
+
+	var stored models.Post
+	global.Db.First(&stored, post.ID)
+	if stored.Title != "Hello" {
+		t.Fatalf("title %q saved without its revision", stored.Title)
+	}
+	if got := revisionNumbers(t, post.ID); len(got) != 1 {
+		t.Fatalf("revisions %v, want only the first", got)
+	}
+}
@@ -0,0 +1,263 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"gogogo/config"
+	"gogogo/global"
+	"gogogo/markdown"
+	"gogogo/models"
+	"gogogo/search"
+	"gogogo/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const searchSnippetRunes = 200
+
+type SearchResultDTO struct {
+	Post       PostDTO            `json:"post"`
+	Score      float64            `json:"score"`
+	Highlights SearchHighlightDTO `json:"highlights"`
+}
+
+// SearchHighlightDTO holds escaped HTML with matches wrapped in <mark>.
+type SearchHighlightDTO struct {
+	Title   string `json:"title"`
+	Summary string `json:"summary,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+type SearchFacetDTO struct {
+	Slug  string `json:"slug"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// SearchPosts ranks published, listed posts for ?q= across title, summary, content
+// and tag names. category, tag and author narrow the results like they do on
+// /api/posts; the facets count the narrowed results. Results, and so total,
+// stop at search.max_results.
+func SearchPosts(ctx *gin.Context) {
+	query := strings.TrimSpace(ctx.Query("q"))
+	if query == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+	if redirectStaleListFilters(ctx) {
+		return
+	}
+
+	page, pageSize := utils.GetPagination(ctx)
+
+	hits, err := searchMatches(query,
+		models.PublishedScope(time.Now()), models.ListedScope, postFilters(ctx))
+	if err != nil {
+		log.Printf("search: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search posts"})
+		return
+	}
+
+	rank := make(map[uint]int, len(hits))
+	ids := make([]uint, 0, len(hits))
+	for i, hit := range hits {
+		rank[hit.ID] = i
+		ids = append(ids, hit.ID)
+	}
+
+	categoryFacets, tagFacets, err := searchFacets(ids)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count facets"})
+		return
+	}
+
+	posts, err := loadSearchPage(hits, page, pageSize)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load posts"})
+		return
+	}
+
+	results := make([]SearchResultDTO, 0, len(posts))
+	for _, post := range posts {
+		access := resolvePostAccess(ctx, post)
+		highlights := SearchHighlightDTO{
+			Title: search.Highlight(post.Title, query, 0),
+		}
+		if access == postReadable {
+			highlights.Content = search.Highlight(markdown.PlainText(post.ContentHTML), query, searchSnippetRunes)
+		}
+		if post.Summary != "" {
+			highlights.Summary = search.Highlight(post.Summary, query, 0)
+		}
+
+		results = append(results, SearchResultDTO{
+			Post:       buildAccessiblePostDTO(post, access, false),
+			Score:      hits[rank[post.ID]].Score,
+			Highlights: highlights,
+		})
+	}
+	refs := make([]*PostDTO, 0, len(results))
+	for i := range results {
+		refs = append(refs, &results[i].Post)
+	}
+	if !respondCommentCounts(ctx, refs) {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":     results,
+		"page":     page,
+		"pageSize": pageSize,
+		"total":    len(ids),
+		"facets": gin.H{
+			"categories": categoryFacets,
+			"tags":       tagFacets,
+		},
+	})
+}
+
+func searchFacets(postIDs []uint) ([]SearchFacetDTO, []SearchFacetDTO, error) {
+	categories := make([]SearchFacetDTO, 0)
+	tags := make([]SearchFacetDTO, 0)
+	if len(postIDs) == 0 {
+		return categories, tags, nil
+	}
+
+	if err := global.Db.Model(&models.Post{}).
+		Select("categories.slug, categories.name, COUNT(*) AS count").
+		Joins("JOIN categories ON categories.id = posts.category_id AND categories.deleted_at IS NULL").
+		Where("posts.id IN ?", postIDs).
+		Group("categories.id, categories.slug, categories.name").
+		Order("count DESC, categories.name ASC").
+		Scan(&categories).Error; err != nil {
+		return nil, nil, err
+	}
+
+	if err := global.Db.Table("post_tags").
+		Select("tags.slug, tags.name, COUNT(*) AS count").
+		Joins("JOIN tags ON tags.id = post_tags.tag_id AND tags.deleted_at IS NULL").
+		Where("post_tags.post_id IN ?", postIDs).
+		Group("tags.id, tags.slug, tags.name").
+		Order("count DESC, tags.name ASC").
+		Scan(&tags).Error; err != nil {
+		return nil, nil, err
+	}
+
+	return categories, tags, nil
+}
+
+// searchScanFactor bounds how far past search.max_results the engine is asked
+// for hits when the scopes filter most of them out.
+const searchScanFactor = 16
+
+// searchMatches ranks the posts matching query that pass scopes, best first,
+// and returns at most search.max_results of them, or all when that is 0. The engine knows nothing of
+// status, visibility or list filters, so when those drop hits it is asked
+// again for a longer list rather than leaving matches below its cut-off out.
+func searchMatches(query string, scopes ...func(*gorm.DB) *gorm.DB) ([]search.Hit, error) {
+	maxResults := config.AppConfig.Search.MaxResults
+	limit := maxResults
+	for {
+		hits, err := global.Search.Search(query, limit)
+		if err != nil {
+			return nil, err
+		}
+
+		hitIDs := make([]uint, 0, len(hits))
+		for _, hit := range hits {
+			hitIDs = append(hitIDs, hit.ID)
+		}
+		var ids []uint
+		if err := global.Db.Model(&models.Post{}).
+			Scopes(scopes...).
+			Where("posts.id IN ?", hitIDs).
+			Pluck("posts.id", &ids).Error; err != nil {
+			return nil, err
+		}
+
+		allowed := make(map[uint]bool, len(ids))
+		for _, id := range ids {
+			allowed[id] = true
+		}
+		matches := make([]search.Hit, 0, len(ids))
+		for _, hit := range hits {
+			if allowed[hit.ID] {
+				matches = append(matches, hit)
+			}
+		}
+
+		if limit <= 0 {
+			return matches, nil
+		}
+		if len(hits) < limit || len(matches) >= maxResults || limit >= maxResults*searchScanFactor {
+			return matches[:min(len(matches), maxResults)], nil
+		}
+		limit *= 4
+	}
+}
+
+// loadSearchPage loads the posts of one page of hits, keeping their rank
+// order.
+func loadSearchPage(hits []search.Hit, page, pageSize int) ([]models.Post, error) {
+	hits = hits[min((page-1)*pageSize, len(hits)):min(page*pageSize, len(hits))]
+	rank := make(map[uint]int, len(hits))
+	ids := make([]uint, 0, len(hits))
+	for i, hit := range hits {
+		rank[hit.ID] = i
+		ids = append(ids, hit.ID)
+	}
+
+	var posts []models.Post
+	if err := global.Db.
+		Preload("Author").
+		Preload("Category").
+		Preload("Tags").
+		Where("id IN ?", ids).
+		Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	sort.Slice(posts, func(i, j int) bool { return rank[posts[i].ID] < rank[posts[j].ID] })
+	return posts, nil
+}
+
+// indexPost updates the search index after a post was saved. Post must have
+// its tags loaded. The post itself is already stored, so failures are only
+// logged; `-reindex` repairs the index.
+func indexPost(post models.Post) {
+	if err := global.Search.Index(search.PostDocument(post)); err != nil {
+		log.Printf("search: failed to index post %d: %v", post.ID, err)
+	}
+}
+
+func removePostFromIndex(postID uint) {
+	if err := global.Search.Remove(postID); err != nil {
+		log.Printf("search: failed to remove post %d: %v", postID, err)
+	}
+}
+
+// reindexPosts refreshes posts whose tag names changed.
+func reindexPosts(postIDs []uint) {
+	if len(postIDs) == 0 {
+		return
+	}
+
+	var posts []models.Post
+	if err := global.Db.Preload("Tags").Where("id IN ?", postIDs).Find(&posts).Error; err != nil {
+		log.Printf("search: failed to load posts for reindexing: %v", err)
+		return
+	}
+	for _, post := range posts {
+		indexPost(post)
+	}
+}
+
+func taggedPostIDs(tagID uint) ([]uint, error) {
+	var ids []uint
+	err := global.Db.Table("post_tags").Where("tag_id = ?", tagID).Pluck("post_id", &ids).Error
+	return ids, err
+}
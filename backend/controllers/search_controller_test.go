@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"gogogo/config"
+	"gogogo/global"
+	"gogogo/models"
+	"gogogo/search"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setupSearchTest publishes an older post with the term in its title and a
+// newer one with it only in the body, and hides a third, best-ranked match
+// in a draft.
+func setupSearchTest(t *testing.T) (*gin.Engine, []models.Post) {
+	t.Helper()
+	setupTestDB(t, allTestModels...)
+
+	author := createTestUser(t, "alice", models.RoleAuthor)
+	titled := createTestPost(t, author, "Gardening", models.PostStatusPublished)
+	mentioned := createTestPost(t, author, "Weekend", models.PostStatusPublished)
+	draft := createTestPost(t, author, "Gardening gardening", models.PostStatusDraft)
+	global.Db.Model(&mentioned).Updates(map[string]interface{}{
+		"content_html": "<p>Some gardening today</p>",
+		"published_at": time.Now().Add(-time.Minute),
+	})
+	global.Db.Model(&titled).Update("published_at", time.Now().Add(-48*time.Hour))
+
+	for _, post := range []models.Post{titled, mentioned, draft} {
+		stored, err := loadPostWithRelations(post.ID)
+		if err != nil {
+			t.Fatalf("load post: %v", err)
+		}
+		if err := global.Search.Index(search.PostDocument(stored)); err != nil {
+			t.Fatalf("index post: %v", err)
+		}
+	}
+
+	router := gin.New()
+	router.GET("/api/posts", ListPosts)
+	router.GET("/api/search", SearchPosts)
+	return router, []models.Post{titled, mentioned, draft}
+}
+
+// resultTitles lists the post titles of a response, reading each post from
+// field when the results wrap it.
+func resultTitles(results []interface{}, field string) []string {
+	titles := make([]string, 0, len(results))
+	for _, result := range results {
+		post := result.(map[string]interface{})
+		if field != "" {
+			post = post[field].(map[string]interface{})
+		}
+		titles = append(titles, post["title"].(string))
+	}
+	return titles
+}
+
+func TestListPostsSearchKeepsRelevanceOrder(t *testing.T) {
+	router, _ := setupSearchTest(t)
+
+	status, body := serveJSON(t, router, http.MethodGet, "/api/posts?search=gardening", nil)
+	if status != http.StatusOK {
+		t.Fatalf("status %d, body %v", status, body)
+	}
+	titles := resultTitles(body["data"].([]interface{}), "")
+	if len(titles) != 2 || titles[0] != "Gardening" || titles[1] != "Weekend" {
+		t.Fatalf("results %v, want the title match before the newer body match", titles)
+	}
+	if body["total"] != float64(2) {
+		t.Fatalf("total = %v, want 2", body["total"])
+	}
+}
+
+func TestSearchPostsFiltersBeforeTheCap(t *testing.T) {
+	router, _ := setupSearchTest(t)
+	config.AppConfig.Search.MaxResults = 1
+
+	status, body := serveJSON(t, router, http.MethodGet, "/api/search?q=gardening", nil)
+	if status != http.StatusOK {
+		t.Fatalf("status %d, body %v", status, body)
+	}
+	titles := resultTitles(body["data"].([]interface{}), "post")
+	if len(titles) != 1 || titles[0] != "Gardening" {
+		t.Fatalf("results %v, want the best published match despite the better draft", titles)
+	}
+}
@@ -0,0 +1,297 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gogogo/global"
+	"gogogo/models"
+	"gogogo/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type refreshRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+type logoutRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type SessionDTO struct {
+	ID         uint      `json:"id"`
+	UserAgent  string    `json:"userAgent"`
+	IPAddress  string    `json:"ipAddress"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	CreatedAt  time.Time `json:"createdAt"`
+	Current    bool      `json:"current"`
+}
+
+type authTokens struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+func RefreshSession(ctx *gin.Context) {
+	var input refreshRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hash := utils.HashToken(strings.TrimSpace(input.RefreshToken))
+	now := time.Now()
+
+	var session models.Session
+	if err := global.Db.Where("refresh_token_hash = ?", hash).First(&session).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load session"})
+			return
+		}
+
+		// A rotated-out token being presented again means it leaked; kill the
+		// whole session so neither party can keep using it.
+		if err := global.Db.Model(&models.Session{}).
+			Where("previous_token_hash = ? AND revoked_at IS NULL", hash).
+			Update("revoked_at", now).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke session"})
+			return
+		}
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
+		return
+	}
+
+	if !session.IsActive(now) {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "session expired or revoked"})
+		return
+	}
+
+	user, err := loadUserByID(ctx, session.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load user"})
+		return
+	}
+	if user.IsSuspended() {
+		respondUserSuspended(ctx)
+		return
+	}
+
+	refreshToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+		return
+	}
+
+	// The swap only succeeds while the presented token is still current, so
+	// of two requests racing with the same token exactly one gets a new pair.
+	result := global.Db.Model(&models.Session{}).
+		Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", session.ID, hash).
+		Updates(map[string]interface{}{
+			"previous_token_hash": hash,
+			"refresh_token_hash":  utils.HashToken(refreshToken),
+			"last_used_at":        now,
+			"expires_at":          now.Add(utils.RefreshTokenTTL()),
+			"user_agent":          truncate(ctx.Request.UserAgent(), 255),
+			"ip_address":          ctx.ClientIP(),
+		})
+	if result.Error != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to rotate session"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		// Someone else rotated this token first: the same reuse as above.
+		if err := global.Db.Model(&models.Session{}).
+			Where("id = ? AND revoked_at IS NULL", session.ID).
+			Update("revoked_at", now).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke session"})
+			return
+		}
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
+		return
+	}
+
+	accessToken, err := utils.GenerateJWT(user.ID, user.Username, user.Role, session.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, authResponse(*user, authTokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    now.Add(utils.AccessTokenTTL()),
+	}))
+}
+
+func Logout(ctx *gin.Context) {
+	var input logoutRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	query := global.Db.Model(&models.Session{}).Where("revoked_at IS NULL")
+	if token := strings.TrimSpace(input.RefreshToken); token != "" {
+		query = query.Where("refresh_token_hash = ?", utils.HashToken(token))
+	} else if _, ok := optionalUserID(ctx); ok {
+		query = query.Where("id = ?", currentSessionID(ctx))
+	} else {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	if err := query.Update("revoked_at", time.Now()).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke session"})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func ListSessions(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	var sessions []models.Session
+	if err := global.Db.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load sessions"})
+		return
+	}
+
+	currentID := currentSessionID(ctx)
+	result := make([]SessionDTO, 0, len(sessions))
+	for _, session := range sessions {
+		result = append(result, SessionDTO{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			CreatedAt:  session.CreatedAt,
+			Current:    session.ID == currentID,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+func RevokeSession(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id"})
+		return
+	}
+
+	result := global.Db.Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke session"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// RevokeOtherSessions signs the user out everywhere except the current device.
+func RevokeOtherSessions(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	if err := revokeUserSessions(userID, currentSessionID(ctx)); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke sessions"})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// issueSession creates a session for user and returns a fresh token pair.
+func issueSession(ctx *gin.Context, user models.User) (authTokens, error) {
+	refreshToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return authTokens{}, err
+	}
+
+	now := time.Now()
+	session := models.Session{
+		UserID:           user.ID,
+		RefreshTokenHash: utils.HashToken(refreshToken),
+		UserAgent:        truncate(ctx.Request.UserAgent(), 255),
+		IPAddress:        ctx.ClientIP(),
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(utils.RefreshTokenTTL()),
+	}
+	if err := global.Db.Create(&session).Error; err != nil {
+		return authTokens{}, err
+	}
+
+	accessToken, err := utils.GenerateJWT(user.ID, user.Username, user.Role, session.ID)
+	if err != nil {
+		return authTokens{}, err
+	}
+
+	return authTokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    now.Add(utils.AccessTokenTTL()),
+	}, nil
+}
+
+// revokeUserSessions revokes every active session of the user except keepID.
+func revokeUserSessions(userID uint, keepID uint) error {
+	query := global.Db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID)
+	if keepID > 0 {
+		query = query.Where("id <> ?", keepID)
+	}
+	return query.Update("revoked_at", time.Now()).Error
+}
+
+func authResponse(user models.User, tokens authTokens) gin.H {
+	return gin.H{
+		"token":        tokens.AccessToken,
+		"refreshToken": tokens.RefreshToken,
+		"expiresAt":    tokens.ExpiresAt,
+		"user":         buildUserDTO(user),
+	}
+}
+
+func truncate(value string, limit int) string {
+	if len(value) <= limit {
+		return value
+	}
+	return value[:limit]
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gogogo/global"
+	"gogogo/models"
+	"gogogo/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func setupSessionTest(t *testing.T) (*gin.Engine, models.Session, string) {
+	t.Helper()
+	setupTestDB(t, &models.User{}, &models.Session{})
+
+	user := createTestUser(t, "alice", models.RoleAuthor)
+	refreshToken := "initial-refresh-token"
+	session := models.Session{
+		UserID:           user.ID,
+		RefreshTokenHash: utils.HashToken(refreshToken),
+		ExpiresAt:        time.Now().Add(utils.RefreshTokenTTL()),
+	}
+	if err := global.Db.Create(&session).Error; err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	router := gin.New()
+	router.POST("/api/auth/refresh", RefreshSession)
+	return router, session, refreshToken
+}
+
+func refresh(t *testing.T, router *gin.Engine, token string) (int, string) {
+	t.Helper()
+	status, body := serveJSON(t, router, http.MethodPost, "/api/auth/refresh", gin.H{"refreshToken": token})
+	next, _ := body["refreshToken"].(string)
+	return status, next
+}
+
+func assertSessionRevoked(t *testing.T, id uint) {
+	t.Helper()
+	var session models.Session
+	if err := global.Db.First(&session, id).Error; err != nil {
+		t.Fatalf("load session: %v", err)
+	}
+	if session.RevokedAt == nil {
+		t.Fatal("session still active after its refresh token was reused")
+	}
+}
+
+func TestRefreshSessionRevokesOnReuse(t *testing.T) {
+	router, session, token := setupSessionTest(t)
+
+	status, next := refresh(t, router, token)
+	if status != http.StatusOK || next == "" || next == token {
+		t.Fatalf("first refresh: status %d, token %q", status, next)
+	}
+
+	if status, _ := refresh(t, router, token); status != http.StatusUnauthorized {
+		t.Fatalf("reused token: status %d, want 401", status)
+	}
+	assertSessionRevoked(t, session.ID)
+
+	if status, _ := refresh(t, router, next); status != http.StatusUnauthorized {
+		t.Fatalf("token of the revoked session: status %d, want 401", status)
+	}
+}
+
+// Both requests read the session while the token is still current; the one
+// that loses the race must be treated as reuse instead of getting a pair too.
+func TestRefreshSessionConcurrentRotationCountsAsReuse(t *testing.T) {
+	router, session, token := setupSessionTest(t)
+
+	var raced atomic.Bool
+	var winnerStatus int
+	err := global.Db.Callback().Update().Before("gorm:begin_transaction").Register("test:race_refresh", func(*gorm.DB) {
+		if raced.CompareAndSwap(false, true) {
+			winnerStatus, _ = refresh(t, router, token)
+		}
+	})
+	if err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+
+	status, next := refresh(t, router, token)
+	if winnerStatus != http.StatusOK {
+		t.Fatalf("competing refresh: status %d, want 200", winnerStatus)
+	}
+	if status != http.StatusUnauthorized || next != "" {
+		t.Fatalf("second rotation of the same token: status %d, token %q", status, next)
+	}
+	assertSessionRevoked(t, session.ID)
+}
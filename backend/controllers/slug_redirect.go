@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"gogogo/global"
+	"gogogo/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// findSlugRedirect returns the entity ID an old slug used to belong to, or
+// gorm.ErrRecordNotFound.
+func findSlugRedirect(entityType string, slug string) (uint, error) {
+	var redirect models.SlugRedirect
+	if err := global.Db.
+		Where("entity_type = ? AND slug = ?", entityType, slug).
+		First(&redirect).Error; err != nil {
+		return 0, err
+	}
+	return redirect.EntityID, nil
+}
+
+// findTagBySlug falls back to the slug history. moved is true when slug is
+// no longer the tag's canonical slug.
+func findTagBySlug(slug string) (tag models.Tag, moved bool, err error) {
+	err = global.Db.Where("slug = ?", slug).First(&tag).Error
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return tag, false, err
+	}
+
+	id, err := findSlugRedirect(models.SlugEntityTag, slug)
+	if err != nil {
+		return tag, false, err
+	}
+	err = global.Db.First(&tag, id).Error
+	return tag, err == nil, err
+}
+
+func findCategoryBySlug(slug string) (category models.Category, moved bool, err error) {
+	err = global.Db.Where("slug = ?", slug).First(&category).Error
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return category, false, err
+	}
+
+	id, err := findSlugRedirect(models.SlugEntityCategory, slug)
+	if err != nil {
+		return category, false, err
+	}
+	err = global.Db.First(&category, id).Error
+	return category, err == nil, err
+}
+
+// respondSlugMoved answers a request for an old slug with 301. The body
+// carries the canonical slug for clients that do not follow redirects, and
+// the query string is kept so pagination and filters survive.
+func respondSlugMoved(ctx *gin.Context, slug string, path string) {
+	location := path
+	if query := ctx.Request.URL.RawQuery; query != "" {
+		location += "?" + query
+	}
+	respondMoved(ctx, location, gin.H{"slug": slug})
+}
+
+// redirectStaleListFilters rewrites ?category= and ?tag= filters that name an
+// old slug. It reports whether a response was written.
+func redirectStaleListFilters(ctx *gin.Context) bool {
+	query := ctx.Request.URL.Query()
+	canonical := gin.H{}
+
+	if slug := query.Get("category"); slug != "" {
+		category, moved, err := findCategoryBySlug(slug)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load category"})
+			return true
+		}
+		if moved {
+			query.Set("category", category.Slug)
+			canonical["category"] = category.Slug
+		}
+	}
+
+	if slug := query.Get("tag"); slug != "" {
+		tag, moved, err := findTagBySlug(slug)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load tag"})
+			return true
+		}
+		if moved {
+			query.Set("tag", tag.Slug)
+			canonical["tag"] = tag.Slug
+		}
+	}
+
+	if len(canonical) == 0 {
+		return false
+	}
+	respondMoved(ctx, ctx.Request.URL.Path+"?"+query.Encode(), canonical)
+	return true
+}
+
+func respondMoved(ctx *gin.Context, location string, body gin.H) {
+	body["error"] = "moved permanently"
+	body["location"] = location
+	ctx.Header("Location", location)
+	ctx.JSON(http.StatusMovedPermanently, body)
+}
+
+func postSlugPath(slug string) string {
+	return "/api/posts/slug/" + url.PathEscape(slug)
+}
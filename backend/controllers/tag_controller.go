@@ -95,6 +95,8 @@ func UpdateTag(ctx *gin.Context) {
 		return
 	}
 
+	previousSlug, previousName := tag.Slug, tag.Name
+
 	var input updateTagRequest
 	if err := ctx.ShouldBindJSON(&input); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -121,11 +123,26 @@ func UpdateTag(ctx *gin.Context) {
 		tag.Slug = slug
 	}
 
-	if err := global.Db.Save(&tag).Error; err != nil {
+	err = global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&tag).Error; err != nil {
+			return err
+		}
+		return models.RecordSlugChange(tx, models.SlugEntityTag, tag.ID, previousSlug, tag.Slug)
+	})
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update tag"})
 		return
 	}
 
+	if tag.Name != previousName {
+		postIDs, err := taggedPostIDs(tag.ID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load tagged posts"})
+			return
+		}
+		reindexPosts(postIDs)
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{"data": TagDTO{
 		ID:        tag.ID,
 		Name:      tag.Name,
@@ -145,6 +162,12 @@ func DeleteTag(ctx *gin.Context) {
 		return
 	}
 
+	postIDs, err := taggedPostIDs(tag.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load tagged posts"})
+		return
+	}
+
 	if err := global.Db.Model(&tag).Association("Posts").Clear(); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to detach tag"})
 		return
@@ -154,6 +177,7 @@ func DeleteTag(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete tag"})
 		return
 	}
+	reindexPosts(postIDs)
 
 	ctx.Status(http.StatusNoContent)
 }
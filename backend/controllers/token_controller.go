@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gogogo/global"
+	"gogogo/middleware"
+	"gogogo/models"
+	"gogogo/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createTokenRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required"`
+	ExpiresInDays int      `json:"expiresInDays"`
+}
+
+type AccessTokenDTO struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+func ListAccessTokens(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	var tokens []models.PersonalAccessToken
+	if err := global.Db.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load tokens"})
+		return
+	}
+
+	result := make([]AccessTokenDTO, 0, len(tokens))
+	for _, token := range tokens {
+		result = append(result, buildAccessTokenDTO(token))
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// CreateAccessToken returns the plain token exactly once; only its hash is kept.
+func CreateAccessToken(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	var input createTokenRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > 100 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "name must be between 1 and 100 characters"})
+		return
+	}
+
+	scopes := make([]string, 0, len(input.Scopes))
+	seen := make(map[string]bool, len(input.Scopes))
+	for _, scope := range input.Scopes {
+		scope = strings.TrimSpace(scope)
+		if !models.IsValidScope(scope) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "unknown scope " + scope})
+			return
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "at least one scope is required"})
+		return
+	}
+
+	if input.ExpiresInDays < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "expiresInDays cannot be negative"})
+		return
+	}
+
+	secret, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+		return
+	}
+	plain := middleware.PersonalAccessTokenPrefix + secret
+
+	token := models.PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: utils.HashToken(plain),
+		Prefix:    plain[:len(middleware.PersonalAccessTokenPrefix)+6],
+		Scopes:    strings.Join(scopes, " "),
+	}
+	if input.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, input.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := global.Db.Create(&token).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create token"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"data":  buildAccessTokenDTO(token),
+		"token": plain,
+	})
+}
+
+func RevokeAccessToken(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid token id"})
+		return
+	}
+
+	result := global.Db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.PersonalAccessToken{})
+	if result.Error != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke token"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "token not found"})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func buildAccessTokenDTO(token models.PersonalAccessToken) AccessTokenDTO {
+	return AccessTokenDTO{
+		ID:         token.ID,
+		Name:       token.Name,
+		Prefix:     token.Prefix,
+		Scopes:     token.ScopeList(),
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+		CreatedAt:  token.CreatedAt,
+	}
+}
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"gogogo/config"
+	"gogogo/global"
+	"gogogo/models"
+	"gogogo/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TrashedPostDTO is a deleted post plus when it goes away for good. PurgeAt
+// is omitted when the trash is never emptied automatically.
+type TrashedPostDTO struct {
+	PostDTO
+	DeletedAt time.Time  `json:"deletedAt"`
+	PurgeAt   *time.Time `json:"purgeAt,omitempty"`
+}
+
+// ListTrash lists the current user's deleted posts, most recently deleted
+// first.
+func ListTrash(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	page, pageSize := utils.GetPagination(ctx)
+	trashed := global.Db.Unscoped().Model(&models.Post{}).
+		Where("author_id = ? AND deleted_at IS NOT NULL", userID)
+
+	var total int64
+	if err := trashed.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count posts"})
+		return
+	}
+
+	var posts []models.Post
+	if err := trashed.Session(&gorm.Session{}).
+		Preload("Author").
+		Preload("Category").
+		Preload("Tags").
+		Order("deleted_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&posts).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load posts"})
+		return
+	}
+
+	response := make([]TrashedPostDTO, 0, len(posts))
+	for _, post := range posts {
+		response = append(response, buildTrashedPostDTO(post))
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":     response,
+		"page":     page,
+		"pageSize": pageSize,
+		"total":    total,
+	})
+}
+
+// RestorePost takes a post out of the trash with its status, slug, tags and
+// comments as they were.
+func RestorePost(ctx *gin.Context) {
+	post, ok := loadTrashedPost(ctx)
+	if !ok {
+		return
+	}
+
+	if err := global.Db.Unscoped().Model(&post).Update("deleted_at", nil).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore post"})
+		return
+	}
+
+	post, err := loadPostWithRelations(post.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load post"})
+		return
+	}
+	indexPost(post)
+
+	ctx.JSON(http.StatusOK, gin.H{"data": buildPostDTO(post, true)})
+}
+
+// PurgePost permanently deletes a post that is already in the trash, along
+// with its comments, tag links, revisions and preview links.
+func PurgePost(ctx *gin.Context) {
+	post, ok := loadTrashedPost(ctx)
+	if !ok {
+		return
+	}
+
+	if err := global.Db.Transaction(func(tx *gorm.DB) error {
+		return models.PurgePost(tx, post.ID)
+	}); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to purge post"})
+		return
+	}
+	removePostFromIndex(post.ID)
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// loadTrashedPost loads the :id post only if it is in the trash. A live post
+// answers 409 so it cannot be purged without being deleted first.
+func loadTrashedPost(ctx *gin.Context) (models.Post, bool) {
+	var post models.Post
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return post, false
+	}
+
+	if err := global.Db.Unscoped().First(&post, id).Error; err != nil {
+		handlePostLoadError(ctx, err)
+		return post, false
+	}
+
+	if !canManagePost(ctx, post) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "not allowed to edit this post"})
+		return post, false
+	}
+
+	if !post.DeletedAt.Valid {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "post is not in the trash"})
+		return post, false
+	}
+	return post, true
+}
+
+func buildTrashedPostDTO(post models.Post) TrashedPostDTO {
+	dto := TrashedPostDTO{
+		PostDTO:   buildPostDTO(post, false),
+		DeletedAt: post.DeletedAt.Time,
+	}
+	if days := config.AppConfig.Posts.TrashRetentionDays; days > 0 {
+		purgeAt := post.DeletedAt.Time.AddDate(0, 0, days)
+		dto.PurgeAt = &purgeAt
+	}
+	return dto
+}
@@ -1,7 +1,14 @@
 package controllers
 
 import (
+	"errors"
+	"log"
 	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"gogogo/global"
 	"gogogo/models"
@@ -11,6 +18,18 @@ import (
 	"gorm.io/gorm"
 )
 
+type updateProfileRequest struct {
+	DisplayName *string `json:"displayName"`
+	Bio         *string `json:"bio"`
+	AvatarURL   *string `json:"avatarUrl"`
+	Email       *string `json:"email"`
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	NewPassword     string `json:"newPassword" binding:"required"`
+}
+
 func GetProfile(ctx *gin.Context) {
 	userID, ok := ctx.Get("userID")
 	if !ok {
@@ -31,6 +50,236 @@ func GetProfile(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"user": buildUserDTO(user)})
 }
 
+func UpdateProfile(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	var input updateProfileRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := loadUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load user"})
+		return
+	}
+
+	if input.DisplayName != nil {
+		displayName := strings.TrimSpace(*input.DisplayName)
+		if displayName == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "display name cannot be empty"})
+			return
+		}
+		if utf8.RuneCountInString(displayName) > 128 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "display name must be at most 128 characters"})
+			return
+		}
+		user.DisplayName = displayName
+	}
+
+	if input.Bio != nil {
+		bio := strings.TrimSpace(*input.Bio)
+		if utf8.RuneCountInString(bio) > 2000 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "bio must be at most 2000 characters"})
+			return
+		}
+		user.Bio = bio
+	}
+
+	if input.AvatarURL != nil {
+		avatarURL := strings.TrimSpace(*input.AvatarURL)
+		if avatarURL != "" && !isHTTPURL(avatarURL) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "avatar url must be an http or https url"})
+			return
+		}
+		if len(avatarURL) > 255 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "avatar url is too long"})
+			return
+		}
+		user.AvatarURL = avatarURL
+	}
+
+	emailChanged := false
+	if input.Email != nil {
+		email := strings.TrimSpace(*input.Email)
+		if email == "" {
+			emailChanged = user.Email != nil
+			user.Email = nil
+			user.EmailVerifiedAt = nil
+		} else {
+			if !isValidEmail(email) {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid email address"})
+				return
+			}
+
+			var count int64
+			if err := global.Db.Model(&models.User{}).
+				Where("email = ? AND id <> ?", email, user.ID).
+				Count(&count).Error; err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to validate email"})
+				return
+			}
+			if count > 0 {
+				ctx.JSON(http.StatusConflict, gin.H{"error": "email already registered"})
+				return
+			}
+			if user.Email == nil || *user.Email != email {
+				emailChanged = true
+				user.Email = &email
+				user.EmailVerifiedAt = nil
+			}
+		}
+	}
+
+	if err := global.Db.Save(user).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update profile"})
+		return
+	}
+
+	if emailChanged {
+		if err := sendVerificationEmail(*user); err != nil {
+			log.Printf("failed to issue verification email for user %d: %v", user.ID, err)
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"user": buildUserDTO(*user)})
+}
+
+// ChangePassword replaces the password after verifying the current one and
+// signs the user out of every other session.
+func ChangePassword(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	var input changePasswordRequest
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(input.NewPassword) < 6 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "password must be at least 6 characters"})
+		return
+	}
+
+	user, err := loadUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load user"})
+		return
+	}
+
+	if !utils.CheckPassword(input.CurrentPassword, user.Password) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "current password is incorrect"})
+		return
+	}
+
+	hashedPwd, err := utils.HashPassword(input.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
+		return
+	}
+
+	if err := global.Db.Model(user).Update("password", hashedPwd).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update password"})
+		return
+	}
+
+	if err := revokeUserSessions(user.ID, currentSessionID(ctx)); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke sessions"})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func GetAuthorProfile(ctx *gin.Context) {
+	user, err := loadUserByUsername(ctx.Param("username"))
+	if err != nil {
+		handleUserLoadError(ctx, err)
+		return
+	}
+
+	published := global.Db.Model(&models.Post{}).
+		Scopes(models.PublishedScope(time.Now()), models.ListedScope).
+		Where("author_id = ?", user.ID)
+
+	var postCount int64
+	if err := published.Session(&gorm.Session{}).Count(&postCount).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load post stats"})
+		return
+	}
+
+	var firstPublishedAt, latestPublishedAt *time.Time
+	if postCount > 0 {
+		var first, latest models.Post
+		if err := published.Session(&gorm.Session{}).
+			Select("published_at").
+			Order("published_at ASC").
+			First(&first).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load post stats"})
+			return
+		}
+		if err := published.Session(&gorm.Session{}).
+			Select("published_at").
+			Order("published_at DESC").
+			First(&latest).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load post stats"})
+			return
+		}
+		firstPublishedAt, latestPublishedAt = first.PublishedAt, latest.PublishedAt
+	}
+
+	// Only comments readers can see count: those on listed, published posts
+	// that are not trashed, leaving out password posts whose comments are
+	// hidden until unlocked.
+	var commentCount int64
+	if err := global.Db.Model(&models.Comment{}).
+		Joins("JOIN posts ON posts.id = comments.post_id AND posts.deleted_at IS NULL").
+		Scopes(models.PublishedScope(time.Now()), models.ListedScope).
+		Where("posts.visibility <> ?", models.PostVisibilityPassword).
+		Where("comments.user_id = ? AND comments.status = ?", user.ID, models.CommentStatusApproved).
+		Count(&commentCount).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load comment stats"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": AuthorProfileDTO{
+		UserDTO:           buildPublicUserDTO(*user),
+		PostCount:         postCount,
+		CommentCount:      commentCount,
+		FirstPublishedAt:  firstPublishedAt,
+		LatestPublishedAt: latestPublishedAt,
+	}})
+}
+
+func ListAuthorPosts(ctx *gin.Context) {
+	user, err := loadUserByUsername(ctx.Param("username"))
+	if err != nil {
+		handleUserLoadError(ctx, err)
+		return
+	}
+
+	listPostsWithScopes(ctx, func(db *gorm.DB) *gorm.DB {
+		return db.Where("posts.author_id = ?", user.ID)
+	})
+}
+
 func ListMyPosts(ctx *gin.Context) {
 	userID, ok := ctx.Get("userID")
 	if !ok {
@@ -66,6 +315,9 @@ func ListMyPosts(ctx *gin.Context) {
 	for _, post := range posts {
 		response = append(response, buildPostDTO(post, false))
 	}
+	if !respondCommentCounts(ctx, postDTORefs(response)) {
+		return
+	}
 
 	ctx.JSON(http.StatusOK, gin.H{
 		"data":     response,
@@ -74,3 +326,19 @@ func ListMyPosts(ctx *gin.Context) {
 		"total":    total,
 	})
 }
+
+func isValidEmail(value string) bool {
+	if len(value) > 128 {
+		return false
+	}
+	addr, err := mail.ParseAddress(value)
+	return err == nil && addr.Address == value
+}
+
+func isHTTPURL(value string) bool {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+	return (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"gogogo/global"
+	"gogogo/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuthorProfileCountsOnlyVisibleComments(t *testing.T) {
+	setupTestDB(t, allTestModels...)
+	author := createTestUser(t, "alice", models.RoleAuthor)
+	commenter := createTestUser(t, "bob", models.RoleAuthor)
+
+	public := createTestPost(t, author, "Public", models.PostStatusPublished)
+	private := createTestPost(t, author, "Private", models.PostStatusPublished)
+	global.Db.Model(&private).Update("visibility", models.PostVisibilityPrivate)
+	protected := createTestPost(t, author, "Protected", models.PostStatusPublished)
+	global.Db.Model(&protected).Update("visibility", models.PostVisibilityPassword)
+	draft := createTestPost(t, author, "Draft", models.PostStatusDraft)
+	trashed := createTestPost(t, author, "Trashed", models.PostStatusPublished)
+
+	for _, post := range []models.Post{public, private, protected, draft, trashed} {
+		comment := createTestComment(t, post, nil, models.CommentStatusApproved)
+		global.Db.Model(&comment).Update("user_id", commenter.ID)
+	}
+	pending := createTestComment(t, public, nil, models.CommentStatusPending)
+	global.Db.Model(&pending).Update("user_id", commenter.ID)
+	global.Db.Delete(&trashed)
+
+	router := gin.New()
+	router.GET("/api/users/:username", GetAuthorProfile)
+	status, body := serveJSON(t, router, http.MethodGet, "/api/users/bob", nil)
+	if status != http.StatusOK {
+		t.Fatalf("status %d, body %v", status, body)
+	}
+	profile := body["data"].(map[string]interface{})
+	if profile["commentCount"] != float64(1) {
+		t.Fatalf("commentCount = %v, want 1", profile["commentCount"])
+	}
+}
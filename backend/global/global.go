@@ -1,9 +1,18 @@
 package global
 
 import (
+	"gogogo/mailer"
+	"gogogo/oidc"
+	"gogogo/search"
+	"gogogo/spam"
+
 	"gorm.io/gorm"
 )
 
 var (
-	Db *gorm.DB
+	Db     *gorm.DB
+	Mailer mailer.Mailer
+	OIDC   *oidc.Registry
+	Search search.Engine
+	Spam   *spam.Filter
 )
@@ -0,0 +1,40 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"gogogo/config"
+)
+
+// Start launches the background workers. They stop when ctx is cancelled.
+func Start(ctx context.Context) {
+	interval := time.Duration(config.AppConfig.Posts.PublishIntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	go runEvery(ctx, "scheduled publisher", interval, publishDuePosts)
+	if config.AppConfig.Posts.TrashRetentionDays > 0 {
+		go runEvery(ctx, "trash purger", trashPurgeInterval, purgeExpiredTrash)
+	}
+}
+
+// runEvery calls task once immediately and then on every tick. Failures are
+// logged and retried on the next tick.
+func runEvery(ctx context.Context, name string, interval time.Duration, task func(time.Time) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := task(time.Now()); err != nil {
+			log.Printf("%s: %v", name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
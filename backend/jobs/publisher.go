@@ -0,0 +1,60 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"gogogo/global"
+	"gogogo/models"
+
+	"gorm.io/gorm"
+)
+
+func publishDuePosts(now time.Time) error {
+	published, err := PublishDuePosts(now)
+	if err != nil {
+		return err
+	}
+	if published > 0 {
+		log.Printf("scheduled publisher: published %d post(s)", published)
+	}
+	return nil
+}
+
+// PublishDuePosts flips scheduled posts whose publish time has passed to
+// published, records the change in each post's history and returns how many
+// were changed.
+func PublishDuePosts(now time.Time) (int64, error) {
+	var published int64
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		if err := tx.Model(&models.Post{}).
+			Where("status = ? AND published_at <= ?", models.PostStatusScheduled, now).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		// Each post is flipped on its own so a post that an editor unscheduled
+		// between the select and the update gets no transition.
+		for _, id := range ids {
+			result := tx.Model(&models.Post{}).
+				Where("id = ? AND status = ?", id, models.PostStatusScheduled).
+				Update("status", models.PostStatusPublished)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				continue
+			}
+			if err := models.RecordTransition(tx, id, nil, models.PostStatusScheduled, models.PostStatusPublished, ""); err != nil {
+				return err
+			}
+			published++
+		}
+		return nil
+	})
+	return published, err
+}
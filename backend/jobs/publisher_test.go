@@ -0,0 +1,79 @@
+package jobs
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gogogo/global"
+	"gogogo/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupJobsTest(t *testing.T) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Post{}, &models.PostTransition{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	global.Db = db
+}
+
+func createScheduledPost(t *testing.T, slug string, publishAt time.Time) models.Post {
+	t.Helper()
+
+	post := models.Post{Title: slug, Slug: slug, Status: models.PostStatusScheduled, PublishedAt: &publishAt}
+	if err := global.Db.Create(&post).Error; err != nil {
+		t.Fatalf("create post: %v", err)
+	}
+	return post
+}
+
+func TestPublishDuePostsRecordsOnlyPublishedPosts(t *testing.T) {
+	setupJobsTest(t)
+	now := time.Now()
+	due := createScheduledPost(t, "due", now.Add(-time.Minute))
+	unscheduled := createScheduledPost(t, "unscheduled", now.Add(-time.Minute))
+	createScheduledPost(t, "later", now.Add(time.Hour))
+
+	// An editor moves one of the due posts back to draft right after the
+	// publisher has selected it.
+	var raced atomic.Bool
+	err := global.Db.Callback().Query().After("gorm:query").Register("test:unschedule", func(db *gorm.DB) {
+		if db.Statement.Table == "posts" && raced.CompareAndSwap(false, true) {
+			db.Session(&gorm.Session{NewDB: true}).Exec("UPDATE posts SET status = ? WHERE id = ?", models.PostStatusDraft, unscheduled.ID)
+		}
+	})
+	if err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+
+	published, err := PublishDuePosts(now)
+	if err != nil {
+		t.Fatalf("PublishDuePosts: %v", err)
+	}
+	if published != 1 {
+		t.Fatalf("published %d posts, want 1", published)
+	}
+
+	var transitions []models.PostTransition
+	global.Db.Find(&transitions)
+	if len(transitions) != 1 || transitions[0].PostID != due.ID {
+		t.Fatalf("transitions %+v, want one for post %d", transitions, due.ID)
+	}
+
+	var stored models.Post
+	global.Db.First(&stored, unscheduled.ID)
+	if stored.Status != models.PostStatusDraft {
+		t.Fatalf("unscheduled post is %s, want draft", stored.Status)
+	}
+}
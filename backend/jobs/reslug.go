@@ -0,0 +1,119 @@
+package jobs
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+
+	"gogogo/global"
+	"gogogo/models"
+	"gogogo/utils"
+
+	"gorm.io/gorm"
+)
+
+type slugRow struct {
+	ID     uint
+	Source string
+	Slug   string
+}
+
+// Reslug regenerates the slugs of posts, categories and tags that were made
+// by the old ASCII-only Slugify, e.g. "item-3" for a Chinese title. Slugs
+// that were typed in by hand are left alone. With dryRun set, changes are
+// only logged.
+func Reslug(dryRun bool) error {
+	targets := []struct {
+		name       string
+		entityType string
+		model      interface{}
+		source     string
+	}{
+		{"posts", models.SlugEntityPost, &models.Post{}, "title"},
+		{"categories", models.SlugEntityCategory, &models.Category{}, "name"},
+		{"tags", models.SlugEntityTag, &models.Tag{}, "name"},
+	}
+
+	for _, target := range targets {
+		changed, err := reslugTable(target.name, target.entityType, target.model, target.source, dryRun)
+		if err != nil {
+			return fmt.Errorf("reslug %s: %w", target.name, err)
+		}
+		if dryRun {
+			log.Printf("reslug: %d %s would be updated (dry run)", changed, target.name)
+		} else {
+			log.Printf("reslug: %d %s updated", changed, target.name)
+		}
+	}
+	return nil
+}
+
+func reslugTable(name string, entityType string, model interface{}, source string, dryRun bool) (int, error) {
+	var rows []slugRow
+	if err := global.Db.Model(model).
+		Select("id", source+" AS source", "slug").
+		Order("id").
+		Scan(&rows).Error; err != nil {
+		return 0, err
+	}
+
+	planned := make(map[string]bool)
+	changed := 0
+	for _, row := range rows {
+		legacy := utils.LegacySlugify(row.Source)
+		base := utils.Slugify(row.Source)
+		if base == legacy || !generatedFrom(row.Slug, legacy) {
+			continue
+		}
+
+		slug, err := availableSlug(model, base, row.ID, planned)
+		if err != nil {
+			return changed, err
+		}
+		planned[slug] = true
+
+		log.Printf("reslug: %s #%d %q -> %q", name, row.ID, row.Slug, slug)
+		if !dryRun {
+			err := global.Db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Model(model).Where("id = ?", row.ID).UpdateColumn("slug", slug).Error; err != nil {
+					return err
+				}
+				// Old links keep working through the slug history.
+				return models.RecordSlugChange(tx, entityType, row.ID, row.Slug, slug)
+			})
+			if err != nil {
+				return changed, err
+			}
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// generatedFrom reports whether slug is legacy, possibly with the numeric
+// suffix added to keep it unique.
+func generatedFrom(slug string, legacy string) bool {
+	if slug == legacy {
+		return true
+	}
+	matched, _ := regexp.MatchString(`^`+regexp.QuoteMeta(legacy)+`-\d+$`, slug)
+	return matched
+}
+
+// availableSlug checks soft-deleted rows too because they still hold their
+// place in the unique index.
+func availableSlug(model interface{}, base string, excludeID uint, planned map[string]bool) (string, error) {
+	candidate := base
+	for i := 1; ; i++ {
+		var count int64
+		if err := global.Db.Unscoped().Model(model).
+			Where("slug = ? AND id <> ?", candidate, excludeID).
+			Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 && !planned[candidate] {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s-%d", base, i)
+	}
+}
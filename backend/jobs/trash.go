@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"gogogo/config"
+	"gogogo/global"
+	"gogogo/models"
+
+	"gorm.io/gorm"
+)
+
+const trashPurgeInterval = time.Hour
+
+func purgeExpiredTrash(now time.Time) error {
+	purged, err := PurgeExpiredTrash(now)
+	if err != nil {
+		return err
+	}
+	if purged > 0 {
+		log.Printf("trash purger: purged %d post(s)", purged)
+	}
+	return nil
+}
+
+// PurgeExpiredTrash permanently deletes posts that have been in the trash for
+// longer than posts.trash_retention_days and returns how many were removed.
+// A retention of 0 keeps trashed posts forever.
+func PurgeExpiredTrash(now time.Time) (int, error) {
+	days := config.AppConfig.Posts.TrashRetentionDays
+	if days <= 0 {
+		return 0, nil
+	}
+
+	var ids []uint
+	if err := global.Db.Unscoped().Model(&models.Post{}).
+		Scopes(models.TrashedBefore(now.AddDate(0, 0, -days))).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	for i, id := range ids {
+		if err := global.Db.Transaction(func(tx *gorm.DB) error {
+			return models.PurgePost(tx, id)
+		}); err != nil {
+			return i, err
+		}
+		if err := global.Search.Remove(id); err != nil {
+			log.Printf("trash purger: failed to remove post %d from search index: %v", id, err)
+		}
+	}
+	return len(ids), nil
+}
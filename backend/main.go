@@ -1,12 +1,42 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"log"
+
 	"gogogo/config"
+	"gogogo/global"
+	"gogogo/jobs"
 	"gogogo/router"
+	"gogogo/search"
 )
 
 func main() {
+	reslug := flag.Bool("reslug", false, "regenerate auto-generated slugs of posts, categories and tags, then exit")
+	dryRun := flag.Bool("dry-run", false, "with -reslug, only log the changes")
+	reindex := flag.Bool("reindex", false, "rebuild the search index from all posts, then exit")
+	flag.Parse()
+
 	config.InitConfig()
+
+	if *reslug {
+		if err := jobs.Reslug(*dryRun); err != nil {
+			log.Fatalf("Failed to reslug: %v", err)
+		}
+		return
+	}
+
+	if *reindex {
+		indexed, err := search.IndexAllPosts(global.Db, global.Search)
+		if err != nil {
+			log.Fatalf("Failed to reindex: %v", err)
+		}
+		log.Printf("reindex: %d posts indexed", indexed)
+		return
+	}
+
+	jobs.Start(context.Background())
 	server := router.SetupRouter()
 
 	server.Run(config.AppConfig.App.Port)
@@ -0,0 +1,167 @@
+package markdown
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+// Heading is one entry of a document's table of contents. ID matches the id
+// attribute of the rendered heading.
+type Heading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+	ID    string `json:"id"`
+}
+
+type Result struct {
+	HTML string
+	TOC  []Heading
+}
+
+var (
+	engine = goldmark.New(
+		goldmark.WithExtensions(extension.GFM, extension.Footnote),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+		// Raw HTML is passed through here and cleaned up by policy below.
+		goldmark.WithRendererOptions(gmhtml.WithUnsafe()),
+	)
+	policy      = newPolicy()
+	stripPolicy = bluemonday.StrictPolicy()
+	spaces      = regexp.MustCompile(`\s+`)
+)
+
+// Render converts CommonMark with GFM tables, task lists, strikethrough,
+// autolinks and footnotes to sanitized HTML.
+func Render(source string) (Result, error) {
+	src := []byte(source)
+	ctx := parser.NewContext(parser.WithIDs(newHeadingIDs()))
+	doc := engine.Parser().Parse(text.NewReader(src), parser.WithContext(ctx))
+
+	var buf bytes.Buffer
+	if err := engine.Renderer().Render(&buf, src, doc); err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		HTML: policy.Sanitize(buf.String()),
+		TOC:  collectHeadings(doc, src),
+	}, nil
+}
+
+// PlainText reduces rendered HTML to its text, e.g. for search snippets.
+func PlainText(rendered string) string {
+	text := html.UnescapeString(stripPolicy.Sanitize(rendered))
+	return strings.TrimSpace(spaces.ReplaceAllString(text, " "))
+}
+
+func collectHeadings(doc ast.Node, src []byte) []Heading {
+	headings := make([]Heading, 0)
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := node.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+
+		id, _ := heading.AttributeString("id")
+		idBytes, _ := id.([]byte)
+		headings = append(headings, Heading{
+			Level: heading.Level,
+			Text:  strings.TrimSpace(plainText(heading, src)),
+			ID:    string(idBytes),
+		})
+		return ast.WalkSkipChildren, nil
+	})
+	return headings
+}
+
+// plainText concatenates the text below node, dropping inline markup.
+func plainText(node ast.Node, src []byte) string {
+	var builder strings.Builder
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		switch n := child.(type) {
+		case *ast.Text:
+			builder.Write(n.Value(src))
+			if n.SoftLineBreak() || n.HardLineBreak() {
+				builder.WriteByte(' ')
+			}
+		case *ast.String:
+			builder.Write(n.Value)
+		default:
+			builder.WriteString(plainText(child, src))
+		}
+	}
+	return builder.String()
+}
+
+// headingIDs derives anchors from heading text so they stay the same across
+// renders. Letters of any script are kept; repeated headings get -1, -2...
+type headingIDs struct {
+	used map[string]bool
+}
+
+func newHeadingIDs() *headingIDs {
+	return &headingIDs{used: make(map[string]bool)}
+}
+
+func (ids *headingIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	var builder strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(string(value)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			if dash && builder.Len() > 0 {
+				builder.WriteByte('-')
+			}
+			builder.WriteRune(r)
+			dash = false
+		case r == '_':
+			builder.WriteRune(r)
+			dash = false
+		default:
+			dash = true
+		}
+	}
+
+	base := builder.String()
+	if base == "" {
+		base = "section"
+	}
+
+	id := base
+	for i := 1; ids.used[id]; i++ {
+		id = base + "-" + strconv.Itoa(i)
+	}
+	ids.used[id] = true
+	return []byte(id)
+}
+
+func (ids *headingIDs) Put(value []byte) {
+	ids.used[string(value)] = true
+}
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+
+	// Heading anchors may contain letters of any script.
+	p.AllowAttrs("id").Matching(regexp.MustCompile(`^[\p{L}\p{N}_:.-]+$`)).OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+
+	// Fenced code language hints, footnotes and GFM task lists.
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^footnotes?(-ref|-backref)?$`)).OnElements("a", "div")
+	p.AllowAttrs("role").Matching(regexp.MustCompile(`^doc-(noteref|endnotes|backlink)$`)).OnElements("a", "div")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").Matching(regexp.MustCompile(`^(|checked|disabled)$`)).OnElements("input")
+	return p
+}
@@ -0,0 +1,72 @@
+package markdown
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func render(t *testing.T, source string) Result {
+	t.Helper()
+	result, err := Render(source)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	return result
+}
+
+func TestRenderTableOfContents(t *testing.T) {
+	result := render(t, "# Intro\n\n## Getting *started*\n\n## Getting started\n\n### 安装 Go\n\n## !!!\n")
+
+	want := []Heading{
+		{Level: 1, Text: "Intro", ID: "intro"},
+		{Level: 2, Text: "Getting started", ID: "getting-started"},
+		{Level: 2, Text: "Getting started", ID: "getting-started-1"},
+		{Level: 3, Text: "安装 Go", ID: "安装-go"},
+		{Level: 2, Text: "!!!", ID: "section"},
+	}
+	if !reflect.DeepEqual(result.TOC, want) {
+		t.Fatalf("TOC = %+v, want %+v", result.TOC, want)
+	}
+	for _, heading := range want {
+		if !strings.Contains(result.HTML, `id="`+heading.ID+`"`) {
+			t.Fatalf("HTML has no anchor %q:\n%s", heading.ID, result.HTML)
+		}
+	}
+}
+
+func TestRenderKeepsGFMMarkup(t *testing.T) {
+	result := render(t, "- [x] done\n\n| a | b |\n|---|---|\n| 1 | 2 |\n\n~~old~~ text[^1]\n\n[^1]: note\n\n```go\nfmt.Println()\n```\n")
+
+	for _, fragment := range []string{
+		`<input checked="" disabled="" type="checkbox"`,
+		"<table>",
+		"<del>old</del>",
+		`class="footnote-ref"`,
+		`<code class="language-go">`,
+	} {
+		if !strings.Contains(result.HTML, fragment) {
+			t.Fatalf("HTML lacks %q:\n%s", fragment, result.HTML)
+		}
+	}
+}
+
+func TestRenderSanitizesHTML(t *testing.T) {
+	result := render(t, "<script>alert(1)</script>\n\n<a href=\"javascript:alert(1)\" onclick=\"steal()\">x</a> <img src=x onerror=alert(1)> <b class=\"x\">ok</b>\n\n[link](javascript:alert(1))\n")
+
+	for _, fragment := range []string{"<script", "javascript:", "onclick", "onerror", `class="x"`} {
+		if strings.Contains(result.HTML, fragment) {
+			t.Fatalf("HTML kept %q:\n%s", fragment, result.HTML)
+		}
+	}
+	if !strings.Contains(result.HTML, "<b>ok</b>") {
+		t.Fatalf("HTML dropped harmless markup:\n%s", result.HTML)
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	result := render(t, "# Title\n\nSome **bold**   &amp; `code`\nacross lines.\n")
+	if got := PlainText(result.HTML); got != "Title Some bold & code across lines." {
+		t.Fatalf("PlainText = %q", got)
+	}
+}
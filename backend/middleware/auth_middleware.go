@@ -1,36 +1,139 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
+	"gogogo/global"
+	"gogogo/models"
 	"gogogo/utils"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// PersonalAccessTokenPrefix marks bearer tokens that are personal access
+// tokens rather than JWTs.
+const PersonalAccessTokenPrefix = "gpat_"
+
+var (
+	ErrMissingToken   = errors.New("authorization header missing")
+	ErrInvalidToken   = errors.New("invalid or expired token")
+	ErrSessionRevoked = errors.New("session has been revoked")
+	ErrUserSuspended  = errors.New("account suspended")
 )
 
 func AuthMiddleware() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		rawHeader := ctx.GetHeader("Authorization")
-		if rawHeader == "" {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authorization header missing"})
+		if err := Authenticate(ctx); err != nil {
+			status := http.StatusUnauthorized
+			if errors.Is(err, ErrUserSuspended) {
+				status = http.StatusForbidden
+			}
+			ctx.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
 			return
 		}
 
-		token := rawHeader
-		if parts := strings.SplitN(rawHeader, " ", 2); len(parts) == 2 {
-			token = parts[1]
+		ctx.Next()
+	}
+}
+
+// Authenticate validates the bearer token on the request and stores the
+// caller's identity on the context. It is shared by AuthMiddleware and by
+// handlers that accept optional authentication.
+func Authenticate(ctx *gin.Context) error {
+	token := bearerToken(ctx)
+	if token == "" {
+		return ErrMissingToken
+	}
+
+	if strings.HasPrefix(token, PersonalAccessTokenPrefix) {
+		return authenticatePersonalAccessToken(ctx, token)
+	}
+
+	claims, err := utils.ValidateJWT(token)
+	if err != nil || claims.SessionID == 0 {
+		return ErrInvalidToken
+	}
+
+	var session models.Session
+	if err := global.Db.Select("id", "user_id", "expires_at", "revoked_at").
+		First(&session, claims.SessionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSessionRevoked
 		}
+		return err
+	}
 
-		claims, err := utils.ValidateJWT(token)
-		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
-			return
+	if session.UserID != claims.UserID || !session.IsActive(time.Now()) {
+		return ErrSessionRevoked
+	}
+
+	// The role is read fresh so that role changes and suspensions apply to
+	// access tokens that were issued before them.
+	var user models.User
+	if err := global.Db.Select("id", "username", "role", "suspended_at").
+		First(&user, claims.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSessionRevoked
 		}
+		return err
+	}
+	if user.IsSuspended() {
+		return ErrUserSuspended
+	}
 
-		ctx.Set("userID", claims.UserID)
-		ctx.Set("username", claims.Username)
+	ctx.Set("userID", user.ID)
+	ctx.Set("username", user.Username)
+	ctx.Set("role", user.Role)
+	ctx.Set("sessionID", claims.SessionID)
+	return nil
+}
 
-		ctx.Next()
+func authenticatePersonalAccessToken(ctx *gin.Context, token string) error {
+	var accessToken models.PersonalAccessToken
+	if err := global.Db.Preload("User").
+		Where("token_hash = ?", utils.HashToken(token)).
+		First(&accessToken).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+
+	now := time.Now()
+	if accessToken.IsExpired(now) || accessToken.User.ID == 0 {
+		return ErrInvalidToken
+	}
+	if accessToken.User.IsSuspended() {
+		return ErrUserSuspended
+	}
+
+	// Avoid a write per request for busy scripts; minute precision is enough.
+	if accessToken.LastUsedAt == nil || now.Sub(*accessToken.LastUsedAt) > time.Minute {
+		if err := global.Db.Model(&accessToken).UpdateColumn("last_used_at", now).Error; err != nil {
+			return err
+		}
+	}
+
+	ctx.Set("userID", accessToken.UserID)
+	ctx.Set("username", accessToken.User.Username)
+	ctx.Set("role", accessToken.User.Role)
+	ctx.Set("tokenScopes", accessToken.ScopeList())
+	return nil
+}
+
+func bearerToken(ctx *gin.Context) string {
+	rawHeader := ctx.GetHeader("Authorization")
+	if rawHeader == "" {
+		return ""
+	}
+
+	token := rawHeader
+	if parts := strings.SplitN(rawHeader, " ", 2); len(parts) == 2 {
+		token = parts[1]
 	}
+	return strings.TrimSpace(token)
 }
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+
+	"gogogo/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole must run after AuthMiddleware. It rejects users whose role ranks
+// below minimum.
+func RequireRole(minimum string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !models.RoleAtLeast(ctx.GetString("role"), minimum) {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient role"})
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// RequirePermission must run after AuthMiddleware. It rejects users whose role
+// does not grant permission.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !models.RoleHasPermission(ctx.GetString("role"), permission) {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -1,14 +1,113 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
 
+	"gorm.io/gorm"
+)
+
+const (
+	CommentStatusPending  = "pending"
+	CommentStatusApproved = "approved"
+	CommentStatusRejected = "rejected"
+	CommentStatusSpam     = "spam"
+)
+
+// Comment is a reader's comment on a post. Replies point at their parent and
+// sit one level deeper, and RootID names the top-level comment of their
+// thread; ReplyCount counts the approved direct replies. Only
+// approved comments are shown to readers. IPAddress, UserAgent and the Spam*
+// fields feed the spam filter and are only shown to moderators; SpamLabel is
+// what the filter was last trained with, if anything. PreviousBody keeps the
+// body as it was before the latest edit, also for moderators.
 type Comment struct {
 	gorm.Model
-	PostID     uint   `json:"postId"`
-	Post       Post   `json:"-"`
-	UserID     *uint  `json:"userId"`
-	User       *User  `json:"user,omitempty"`
-	AuthorName string `gorm:"size:128" json:"authorName"`
-	Body       string `gorm:"type:text" json:"body"`
-	Approved   bool   `json:"approved"`
+	PostID        uint       `json:"postId"`
+	Post          Post       `json:"-"`
+	UserID        *uint      `json:"userId"`
+	User          *User      `json:"user,omitempty"`
+	AuthorName    string     `gorm:"size:128" json:"authorName"`
+	Body          string     `gorm:"type:text" json:"body"`
+	Status        string     `gorm:"size:16;not null;default:approved;index" json:"status"`
+	ModeratedByID *uint      `json:"moderatedById,omitempty"`
+	ModeratedAt   *time.Time `json:"moderatedAt,omitempty"`
+	ParentID      *uint      `gorm:"index" json:"parentId"`
+	RootID        *uint      `gorm:"index" json:"rootId"`
+	Depth         int        `gorm:"not null;default:0" json:"depth"`
+	ReplyCount    int        `gorm:"not null;default:0" json:"replyCount"`
+	IPAddress     string     `gorm:"size:45;index" json:"-"`
+	UserAgent     string     `gorm:"size:255" json:"-"`
+	BodyHash      string     `gorm:"size:64;index" json:"-"`
+	SpamScore     float64    `gorm:"not null;default:0" json:"-"`
+	SpamReasons   string     `gorm:"size:512" json:"-"`
+	SpamLabel     string     `gorm:"size:8" json:"-"`
+	EditedAt      *time.Time `json:"editedAt,omitempty"`
+	PreviousBody  string     `gorm:"type:text" json:"-"`
+}
+
+func (c Comment) IsApproved() bool {
+	return c.Status == CommentStatusApproved
+}
+
+// IsTombstone reports whether the comment was deleted but is still shown,
+// without its content, because it has replies.
+func (c Comment) IsTombstone() bool {
+	return c.DeletedAt.Valid
+}
+
+// WithTombstones includes deleted comments that still have a reply in one of
+// statuses, the ones the viewer can see, so those replies keep their place in
+// the thread.
+func WithTombstones(statuses []string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped().Where("comments.deleted_at IS NULL OR EXISTS (?)",
+			db.Session(&gorm.Session{NewDB: true}).Table("comments AS replies").
+				Select("1").
+				Where("replies.parent_id = comments.id AND replies.deleted_at IS NULL AND replies.status IN ?", statuses))
+	}
+}
+
+// HashCommentBody fingerprints a comment body so resubmissions are found
+// regardless of case and whitespace.
+func HashCommentBody(body string) string {
+	normalised := strings.Join(strings.Fields(strings.ToLower(body)), " ")
+	sum := sha256.Sum256([]byte(normalised))
+	return hex.EncodeToString(sum[:])
+}
+
+// RecountReplies recomputes ReplyCount for the given comments from their
+// approved, undeleted replies. Deleted comments are recounted too, since
+// tombstones still show their count.
+func RecountReplies(tx *gorm.DB, commentIDs []uint) error {
+	if len(commentIDs) == 0 {
+		return nil
+	}
+
+	var counts []struct {
+		ParentID uint
+		Total    int
+	}
+	if err := tx.Model(&Comment{}).
+		Select("parent_id, COUNT(*) AS total").
+		Where("parent_id IN ? AND status = ?", commentIDs, CommentStatusApproved).
+		Group("parent_id").
+		Scan(&counts).Error; err != nil {
+		return err
+	}
+
+	totals := make(map[uint]int, len(counts))
+	for _, count := range counts {
+		totals[count.ParentID] = count.Total
+	}
+	for _, id := range commentIDs {
+		if err := tx.Unscoped().Model(&Comment{}).
+			Where("id = ?", id).
+			UpdateColumn("reply_count", totals[id]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LoginThrottle counts recent failed logins for one key, either a username
+// ("user:<name>") or a client address ("ip:<addr>").
+type LoginThrottle struct {
+	gorm.Model
+	Key          string     `gorm:"column:throttle_key;size:191;uniqueIndex"`
+	Failures     int        `gorm:"not null;default:0"`
+	LastFailedAt time.Time  `json:"lastFailedAt"`
+	LockedUntil  *time.Time `json:"lockedUntil"`
+}
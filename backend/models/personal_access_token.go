@@ -0,0 +1,54 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ScopePostsWrite       = "posts:write"
+	ScopeTaxonomyWrite    = "taxonomy:write"
+	ScopeCommentsWrite    = "comments:write"
+	ScopeCommentsModerate = "comments:moderate"
+)
+
+// TokenScopes lists every scope a personal access token may be granted.
+var TokenScopes = []string{
+	ScopePostsWrite,
+	ScopeTaxonomyWrite,
+	ScopeCommentsWrite,
+	ScopeCommentsModerate,
+}
+
+// PersonalAccessToken lets scripts call the API without a password. Only a
+// hash of the token is stored; Prefix helps users tell tokens apart.
+type PersonalAccessToken struct {
+	gorm.Model
+	UserID     uint       `gorm:"index" json:"userId"`
+	User       User       `json:"-"`
+	Name       string     `gorm:"size:100" json:"name"`
+	TokenHash  string     `gorm:"size:64;uniqueIndex" json:"-"`
+	Prefix     string     `gorm:"size:16" json:"prefix"`
+	Scopes     string     `gorm:"size:255" json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+}
+
+func IsValidScope(scope string) bool {
+	for _, candidate := range TokenScopes {
+		if candidate == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *PersonalAccessToken) ScopeList() []string {
+	return strings.Fields(t.Scopes)
+}
+
+func (t *PersonalAccessToken) IsExpired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
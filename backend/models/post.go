@@ -3,32 +3,90 @@ package models
 import (
 	"time"
 
+	"gogogo/markdown"
+
 	"gorm.io/gorm"
 )
 
 const (
-	PostStatusDraft     = "draft"
-	PostStatusPublished = "published"
-	PostStatusArchived  = "archived"
+	PostStatusDraft            = "draft"
+	PostStatusPendingReview    = "pending_review"
+	PostStatusChangesRequested = "changes_requested"
+	PostStatusApproved         = "approved"
+	PostStatusPublished        = "published"
+	PostStatusScheduled        = "scheduled"
+	PostStatusArchived         = "archived"
+)
+
+// Visibility decides who may read a post once it is published. Unlisted
+// posts are readable by anyone with the link but never listed; private posts
+// are only for their author and editors; password posts show their summary
+// until the reader unlocks them.
+const (
+	PostVisibilityPublic   = "public"
+	PostVisibilityUnlisted = "unlisted"
+	PostVisibilityPrivate  = "private"
+	PostVisibilityPassword = "password"
 )
 
 type Post struct {
 	gorm.Model
-	Title       string     `gorm:"size:200;not null"`
-	Summary     string     `gorm:"size:512"`
-	Content     string     `gorm:"type:longtext"`
-	Slug        string     `gorm:"size:200;uniqueIndex"`
-	Status      string     `gorm:"size:32;default:draft"`
-	CoverImage  string     `gorm:"size:255"`
-	PublishedAt *time.Time `json:"publishedAt"`
-	AuthorID    uint       `json:"authorId"`
-	Author      User       `json:"author"`
-	CategoryID  *uint      `json:"categoryId"`
-	Category    *Category  `json:"category"`
-	Tags        []Tag      `gorm:"many2many:post_tags" json:"tags"`
-	Comments    []Comment  `json:"comments"`
+	Title       string             `gorm:"size:200;not null"`
+	Summary     string             `gorm:"size:512"`
+	Content     string             `gorm:"type:longtext"`
+	ContentHTML string             `gorm:"type:longtext"`
+	TOC         []markdown.Heading `gorm:"serializer:json;type:text"`
+	Slug        string             `gorm:"size:200;uniqueIndex"`
+	Status      string             `gorm:"size:32;default:draft"`
+	Visibility  string             `gorm:"size:16;default:public;index"`
+	Password    string             `gorm:"size:255" json:"-"`
+	CoverImage  string             `gorm:"size:255"`
+	PublishedAt *time.Time         `json:"publishedAt"`
+	AuthorID    uint               `json:"authorId"`
+	Author      User               `json:"author"`
+	CategoryID  *uint              `json:"categoryId"`
+	Category    *Category          `json:"category"`
+	Tags        []Tag              `gorm:"many2many:post_tags" json:"tags"`
+	Comments    []Comment          `json:"comments"`
+}
+
+// RenderContent refreshes ContentHTML and TOC from Content. Call it whenever
+// Content changes.
+func (p *Post) RenderContent() error {
+	result, err := markdown.Render(p.Content)
+	if err != nil {
+		return err
+	}
+	p.ContentHTML = result.HTML
+	p.TOC = result.TOC
+	return nil
 }
 
 func (p *Post) IsPublished() bool {
-	return p.Status == PostStatusPublished && p.PublishedAt != nil
+	return p.IsPublishedAt(time.Now())
+}
+
+// IsPublishedAt reports whether the post is publicly visible at now. A
+// scheduled post whose time has come counts even before the background
+// publisher has flipped its status.
+func (p *Post) IsPublishedAt(now time.Time) bool {
+	if p.Status != PostStatusPublished && p.Status != PostStatusScheduled {
+		return false
+	}
+	return p.PublishedAt != nil && !p.PublishedAt.After(now)
+}
+
+// PublishedScope restricts a posts query to publicly visible posts, using the
+// same rule as IsPublishedAt.
+func PublishedScope(now time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("posts.status IN ? AND posts.published_at IS NOT NULL AND posts.published_at <= ?",
+			[]string{PostStatusPublished, PostStatusScheduled}, now)
+	}
+}
+
+// ListedScope restricts a posts query to the visibilities that appear in
+// public listings and search. Combine it with PublishedScope.
+func ListedScope(db *gorm.DB) *gorm.DB {
+	return db.Where("posts.visibility IN ?", []string{PostVisibilityPublic, PostVisibilityPassword})
 }
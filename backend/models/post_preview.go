@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PostPreview is a shareable link that lets anyone holding it read a post in
+// whatever status it is in. The link carries a signed token; only the token
+// ID is stored, so the link itself cannot be recovered from the database.
+type PostPreview struct {
+	gorm.Model
+	PostID       uint       `gorm:"index" json:"postId"`
+	Post         Post       `json:"-"`
+	CreatedByID  uint       `json:"createdById"`
+	CreatedBy    User       `json:"-"`
+	Label        string     `gorm:"size:100" json:"label"`
+	TokenID      string     `gorm:"size:64;uniqueIndex" json:"-"`
+	ExpiresAt    time.Time  `json:"expiresAt"`
+	RevokedAt    *time.Time `json:"revokedAt"`
+	ViewCount    int        `json:"viewCount"`
+	LastViewedAt *time.Time `json:"lastViewedAt"`
+}
+
+func (p *PostPreview) IsActive(now time.Time) bool {
+	return p.RevokedAt == nil && now.Before(p.ExpiresAt)
+}
+
+// PostPreviewView logs one read of a post through a preview link.
+type PostPreviewView struct {
+	gorm.Model
+	PreviewID uint   `gorm:"index" json:"previewId"`
+	PostID    uint   `gorm:"index" json:"postId"`
+	IPAddress string `gorm:"size:64" json:"ipAddress"`
+	UserAgent string `gorm:"size:255" json:"userAgent"`
+}
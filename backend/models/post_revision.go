@@ -0,0 +1,23 @@
+package models
+
+import "gorm.io/gorm"
+
+// PostRevision is an immutable snapshot of a post's editable fields, taken
+// every time the post is created, updated or restored.
+type PostRevision struct {
+	gorm.Model
+	PostID       uint     `gorm:"uniqueIndex:idx_post_revisions_post_number"`
+	Number       int      `gorm:"uniqueIndex:idx_post_revisions_post_number"`
+	EditorID     uint     `gorm:"index"`
+	Editor       User     `json:"editor"`
+	Title        string   `gorm:"size:200"`
+	Summary      string   `gorm:"size:512"`
+	Content      string   `gorm:"type:longtext"`
+	Slug         string   `gorm:"size:200"`
+	Status       string   `gorm:"size:32"`
+	CoverImage   string   `gorm:"size:255"`
+	CategoryID   *uint    `json:"categoryId"`
+	CategoryName string   `gorm:"size:100"`
+	TagNames     []string `gorm:"serializer:json;type:text"`
+	RestoredFrom *int     `json:"restoredFrom"`
+}
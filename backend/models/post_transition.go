@@ -0,0 +1,30 @@
+package models
+
+import "gorm.io/gorm"
+
+// PostTransition records one change of a post's status. ActorID is nil when
+// the change was made by a background job, such as the scheduled publisher.
+type PostTransition struct {
+	gorm.Model
+	PostID     uint   `gorm:"index"`
+	ActorID    *uint  `gorm:"index"`
+	Actor      *User  `json:"actor"`
+	FromStatus string `gorm:"size:32"`
+	ToStatus   string `gorm:"size:32;index"`
+	Notes      string `gorm:"type:text"`
+}
+
+// RecordTransition stores a status change. Unchanged statuses are ignored so
+// callers can record unconditionally after every save.
+func RecordTransition(tx *gorm.DB, postID uint, actorID *uint, from, to, notes string) error {
+	if from == to {
+		return nil
+	}
+	return tx.Create(&PostTransition{
+		PostID:     postID,
+		ActorID:    actorID,
+		FromStatus: from,
+		ToStatus:   to,
+		Notes:      notes,
+	}).Error
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RecoveryCode is a one-time fallback for a lost authenticator.
+type RecoveryCode struct {
+	gorm.Model
+	UserID   uint       `gorm:"index"`
+	User     User       `json:"-"`
+	CodeHash string     `gorm:"size:64;index" json:"-"`
+	UsedAt   *time.Time `json:"usedAt"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Session backs a refresh token. Access tokens carry the session ID so that
+// revoking the session invalidates them before they expire.
+type Session struct {
+	gorm.Model
+	UserID            uint       `gorm:"index" json:"userId"`
+	User              User       `json:"-"`
+	RefreshTokenHash  string     `gorm:"size:64;uniqueIndex" json:"-"`
+	PreviousTokenHash string     `gorm:"size:64;index" json:"-"`
+	UserAgent         string     `gorm:"size:255" json:"userAgent"`
+	IPAddress         string     `gorm:"size:64" json:"ipAddress"`
+	LastUsedAt        time.Time  `json:"lastUsedAt"`
+	ExpiresAt         time.Time  `json:"expiresAt"`
+	RevokedAt         *time.Time `json:"revokedAt"`
+}
+
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
@@ -0,0 +1,36 @@
+package models
+
+import "gorm.io/gorm"
+
+const (
+	SlugEntityPost     = "post"
+	SlugEntityCategory = "category"
+	SlugEntityTag      = "tag"
+)
+
+// SlugRedirect remembers a slug an entity used to have. It points at the
+// entity rather than at the next slug, so repeated renames never form chains.
+type SlugRedirect struct {
+	gorm.Model
+	EntityType string `gorm:"size:32;uniqueIndex:idx_slug_redirects_entity_slug"`
+	Slug       string `gorm:"size:200;uniqueIndex:idx_slug_redirects_entity_slug"`
+	EntityID   uint   `gorm:"index"`
+}
+
+// RecordSlugChange keeps oldSlug pointing at the entity after it was renamed
+// to newSlug. A slug that is live again no longer needs a redirect.
+func RecordSlugChange(tx *gorm.DB, entityType string, entityID uint, oldSlug string, newSlug string) error {
+	if oldSlug == newSlug {
+		return nil
+	}
+
+	if err := tx.Unscoped().
+		Where("entity_type = ? AND slug IN ?", entityType, []string{oldSlug, newSlug}).
+		Delete(&SlugRedirect{}).Error; err != nil {
+		return err
+	}
+	if oldSlug == "" {
+		return nil
+	}
+	return tx.Create(&SlugRedirect{EntityType: entityType, Slug: oldSlug, EntityID: entityID}).Error
+}
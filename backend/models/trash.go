@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TrashedBefore restricts an unscoped posts query to posts that were moved to
+// the trash before cutoff.
+func TrashedBefore(cutoff time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("posts.deleted_at IS NOT NULL AND posts.deleted_at < ?", cutoff)
+	}
+}
+
+// PurgePost permanently deletes a post together with its tag links,
+// comments, revisions, preview links, status history and slug history.
+func PurgePost(tx *gorm.DB, id uint) error {
+	if err := tx.Table("post_tags").Where("post_id = ?", id).Delete(map[string]interface{}{}).Error; err != nil {
+		return err
+	}
+
+	for _, model := range []interface{}{
+		&Comment{},
+		&PostRevision{},
+		&PostPreviewView{},
+		&PostPreview{},
+		&PostTransition{},
+	} {
+		if err := tx.Unscoped().Where("post_id = ?", id).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Unscoped().
+		Where("entity_type = ? AND entity_id = ?", SlugEntityPost, id).
+		Delete(&SlugRedirect{}).Error; err != nil {
+		return err
+	}
+
+	return tx.Unscoped().Delete(&Post{}, id).Error
+}
@@ -1,15 +1,95 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	RoleAdmin       = "admin"
+	RoleEditor      = "editor"
+	RoleAuthor      = "author"
+	RoleContributor = "contributor"
+	RoleReader      = "reader"
+)
+
+const (
+	PermissionWritePosts       = "posts.write"
+	PermissionPublishPosts     = "posts.publish"
+	PermissionEditOthersPosts  = "posts.edit_others"
+	PermissionManageTaxonomy   = "taxonomy.manage"
+	PermissionModerateComments = "comments.moderate"
+	PermissionManageUsers      = "users.manage"
+)
+
+var roleRanks = map[string]int{
+	RoleReader:      1,
+	RoleContributor: 2,
+	RoleAuthor:      3,
+	RoleEditor:      4,
+	RoleAdmin:       5,
+}
+
+// permissionMinimumRoles maps each permission to the lowest role granting it.
+var permissionMinimumRoles = map[string]string{
+	PermissionWritePosts:       RoleContributor,
+	PermissionPublishPosts:     RoleAuthor,
+	PermissionEditOthersPosts:  RoleEditor,
+	PermissionManageTaxonomy:   RoleEditor,
+	PermissionModerateComments: RoleEditor,
+	PermissionManageUsers:      RoleAdmin,
+}
 
 type User struct {
 	gorm.Model
-	Username    string    `gorm:"size:64;uniqueIndex"`
-	Email       *string   `gorm:"size:128;uniqueIndex"`
-	Password    string    `json:"-"`
-	DisplayName string    `gorm:"size:128"`
-	Bio         string    `gorm:"type:text"`
-	AvatarURL   string    `gorm:"size:255"`
-	Posts       []Post    `gorm:"foreignKey:AuthorID" json:"-"`
-	Comments    []Comment `gorm:"foreignKey:UserID" json:"-"`
+	Username        string     `gorm:"size:64;uniqueIndex"`
+	Email           *string    `gorm:"size:128;uniqueIndex"`
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt"`
+	Password        string     `json:"-"`
+	Role            string     `gorm:"size:32;not null;default:author;index"`
+	TOTPSecret      string     `gorm:"size:64" json:"-"`
+	TOTPEnabledAt   *time.Time `json:"-"`
+	TOTPLastStep    int64      `json:"-"`
+	SuspendedAt     *time.Time `json:"suspendedAt"`
+	SuspendedReason string     `gorm:"size:255" json:"suspendedReason"`
+	DisplayName     string     `gorm:"size:128"`
+	Bio             string     `gorm:"type:text"`
+	AvatarURL       string     `gorm:"size:255"`
+	Posts           []Post     `gorm:"foreignKey:AuthorID" json:"-"`
+	Comments        []Comment  `gorm:"foreignKey:UserID" json:"-"`
+}
+
+func IsValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// RoleAtLeast reports whether role ranks at or above minimum.
+func RoleAtLeast(role string, minimum string) bool {
+	rank, ok := roleRanks[role]
+	if !ok {
+		return false
+	}
+	return rank >= roleRanks[minimum]
+}
+
+func RoleHasPermission(role string, permission string) bool {
+	minimum, ok := permissionMinimumRoles[permission]
+	if !ok {
+		return false
+	}
+	return RoleAtLeast(role, minimum)
+}
+
+func (u *User) IsSuspended() bool {
+	return u.SuspendedAt != nil
+}
+
+func (u *User) MFAEnabled() bool {
+	return u.TOTPEnabledAt != nil && u.TOTPSecret != ""
+}
+
+func (u *User) HasPermission(permission string) bool {
+	return RoleHasPermission(u.Role, permission)
 }
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserIdentity links a user to an account at an external OpenID Connect
+// provider. The provider's subject is stable; the email is only a snapshot.
+type UserIdentity struct {
+	gorm.Model
+	UserID      uint      `gorm:"index"`
+	User        User      `json:"-"`
+	Provider    string    `gorm:"size:64;uniqueIndex:idx_user_identities_provider_subject"`
+	Subject     string    `gorm:"size:191;uniqueIndex:idx_user_identities_provider_subject"`
+	Email       string    `gorm:"size:128"`
+	LastLoginAt time.Time `json:"lastLoginAt"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposeMFAChallenge      = "mfa_challenge"
+	TokenPurposePostUnlock        = "post_unlock"
+	TokenPurposePostPreview       = "post_preview"
+)
+
+// UserToken records a single-use action token so it can be consumed exactly
+// once. The token itself is signed; only its ID is stored.
+type UserToken struct {
+	gorm.Model
+	UserID    uint       `gorm:"index"`
+	User      User       `json:"-"`
+	Purpose   string     `gorm:"size:32;index"`
+	TokenID   string     `gorm:"size:64;uniqueIndex"`
+	Email     string     `gorm:"size:128"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt"`
+}
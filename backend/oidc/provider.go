@@ -0,0 +1,204 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown identity provider")
+	ErrNonceMismatch   = errors.New("id token nonce does not match")
+)
+
+type ProviderConfig struct {
+	Name         string
+	DisplayName  string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Identity is what the application needs from a verified ID token.
+type Identity struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+	Picture           string
+}
+
+// Provider wraps one OpenID Connect issuer. Discovery happens on first use so
+// an unreachable identity provider does not prevent the server from starting.
+type Provider struct {
+	config ProviderConfig
+	client *http.Client
+
+	mu       sync.Mutex
+	oauth    *oauth2.Config
+	verifier *gooidc.IDTokenVerifier
+}
+
+func NewProvider(config ProviderConfig) *Provider {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"profile", "email"}
+	}
+	if config.DisplayName == "" {
+		config.DisplayName = config.Name
+	}
+	return &Provider{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// NewVerifier returns a random PKCE code verifier.
+func NewVerifier() string {
+	return oauth2.GenerateVerifier()
+}
+
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+func (p *Provider) DisplayName() string {
+	return p.config.DisplayName
+}
+
+// AuthCodeURL returns the authorization endpoint URL for the code flow with a
+// S256 PKCE challenge derived from verifier.
+func (p *Provider) AuthCodeURL(ctx context.Context, state string, nonce string, verifier string) (string, error) {
+	oauthConfig, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return oauthConfig.AuthCodeURL(state, gooidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Exchange redeems an authorization code and verifies the returned ID token,
+// including its nonce.
+func (p *Provider) Exchange(ctx context.Context, code string, verifier string, nonce string) (*Identity, error) {
+	oauthConfig, idVerifier, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = gooidc.ClientContext(ctx, p.client)
+	token, err := oauthConfig.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("exchange code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+
+	idToken, err := idVerifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("verify id token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, ErrNonceMismatch
+	}
+
+	var claims struct {
+		Email             string `json:"email"`
+		EmailVerified     *bool  `json:"email_verified"`
+		Name              string `json:"name"`
+		PreferredUsername string `json:"preferred_username"`
+		Picture           string `json:"picture"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("decode id token claims: %w", err)
+	}
+
+	return &Identity{
+		Subject:           idToken.Subject,
+		Email:             strings.TrimSpace(claims.Email),
+		EmailVerified:     claims.EmailVerified != nil && *claims.EmailVerified,
+		Name:              claims.Name,
+		PreferredUsername: claims.PreferredUsername,
+		Picture:           claims.Picture,
+	}, nil
+}
+
+func (p *Provider) discover(ctx context.Context) (*oauth2.Config, *gooidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.oauth != nil {
+		return p.oauth, p.verifier, nil
+	}
+
+	provider, err := gooidc.NewProvider(gooidc.ClientContext(ctx, p.client), p.config.Issuer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("discover %s: %w", p.config.Name, err)
+	}
+
+	p.oauth = &oauth2.Config{
+		ClientID:     p.config.ClientID,
+		ClientSecret: p.config.ClientSecret,
+		RedirectURL:  p.config.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       append([]string{gooidc.ScopeOpenID}, p.config.Scopes...),
+	}
+	p.verifier = provider.Verifier(&gooidc.Config{ClientID: p.config.ClientID})
+	return p.oauth, p.verifier, nil
+}
+
+// Registry holds the configured providers in configuration order.
+type Registry struct {
+	providers map[string]*Provider
+	order     []string
+}
+
+func NewRegistry(configs []ProviderConfig) (*Registry, error) {
+	registry := &Registry{providers: make(map[string]*Provider, len(configs))}
+	for _, config := range configs {
+		name := strings.ToLower(strings.TrimSpace(config.Name))
+		if name == "" || config.Issuer == "" || config.ClientID == "" || config.RedirectURL == "" {
+			return nil, fmt.Errorf("oidc provider %q needs name, issuer, client_id and redirect_url", config.Name)
+		}
+		if _, exists := registry.providers[name]; exists {
+			return nil, fmt.Errorf("duplicate oidc provider %q", name)
+		}
+
+		config.Name = name
+		registry.providers[name] = NewProvider(config)
+		registry.order = append(registry.order, name)
+	}
+	return registry, nil
+}
+
+func (r *Registry) Get(name string) (*Provider, error) {
+	if r == nil {
+		return nil, ErrUnknownProvider
+	}
+	provider, ok := r.providers[strings.ToLower(name)]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return provider, nil
+}
+
+func (r *Registry) List() []*Provider {
+	if r == nil {
+		return nil
+	}
+	result := make([]*Provider, 0, len(r.order))
+	for _, name := range r.order {
+		result = append(result, r.providers[name])
+	}
+	return result
+}
@@ -1,12 +1,14 @@
 package router
 
 import (
+	"log"
 	"net/http"
 	"time"
 
 	"gogogo/config"
 	"gogogo/controllers"
 	"gogogo/middleware"
+	"gogogo/models"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -15,9 +17,15 @@ import (
 func SetupRouter() *gin.Engine {
 	server := gin.Default()
 
+	// Forwarding headers are only honoured from listed proxies, otherwise any
+	// client could pick the IP that throttling and spam checks key on.
+	if err := server.SetTrustedProxies(config.AppConfig.App.TrustedProxies); err != nil {
+		log.Fatalf("Invalid app.trusted_proxies: %v", err)
+	}
+
 	corsConfig := cors.Config{
 		AllowMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions},
-		AllowHeaders: []string{"Origin", "Content-Type", "Authorization"},
+		AllowHeaders: []string{"Origin", "Content-Type", "Authorization", "X-Post-Token"},
 		ExposeHeaders: []string{
 			"Content-Length",
 		},
@@ -38,6 +46,15 @@ func SetupRouter() *gin.Engine {
 	auth := api.Group("/auth")
 	auth.POST("/login", controllers.Login)
 	auth.POST("/register", controllers.Register)
+	auth.POST("/refresh", controllers.RefreshSession)
+	auth.POST("/logout", controllers.Logout)
+	auth.POST("/forgot-password", controllers.ForgotPassword)
+	auth.POST("/reset-password", controllers.ResetPassword)
+	auth.GET("/verify-email", controllers.VerifyEmail)
+	auth.POST("/mfa", controllers.CompleteMFALogin)
+	auth.GET("/oidc/providers", controllers.ListOIDCProviders)
+	auth.GET("/oidc/:provider/login", controllers.BeginOIDCLogin)
+	auth.GET("/oidc/:provider/callback", controllers.OIDCCallback)
 
 	api.GET("/health", func(ctx *gin.Context) {
 		ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
@@ -48,26 +65,107 @@ func SetupRouter() *gin.Engine {
 	{
 		protected.GET("/me", controllers.GetProfile)
 		protected.GET("/me/posts", controllers.ListMyPosts)
-
-		protected.POST("/posts", controllers.CreatePost)
-		protected.PUT("/posts/:id", controllers.UpdatePost)
-		protected.DELETE("/posts/:id", controllers.DeletePost)
-
-		protected.POST("/categories", controllers.CreateCategory)
-		protected.PUT("/categories/:id", controllers.UpdateCategory)
-		protected.DELETE("/categories/:id", controllers.DeleteCategory)
-
-		protected.POST("/tags", controllers.CreateTag)
-		protected.PUT("/tags/:id", controllers.UpdateTag)
-		protected.DELETE("/tags/:id", controllers.DeleteTag)
+		protected.GET("/me/trash", controllers.ListTrash)
+
+		account := protected.Group("/me", middleware.RequireSession())
+		account.PUT("", controllers.UpdateProfile)
+		account.POST("/password", controllers.ChangePassword)
+		account.POST("/verify-email", controllers.ResendVerificationEmail)
+		account.GET("/sessions", controllers.ListSessions)
+		account.DELETE("/sessions", controllers.RevokeOtherSessions)
+		account.DELETE("/sessions/:id", controllers.RevokeSession)
+		account.GET("/tokens", controllers.ListAccessTokens)
+		account.POST("/tokens", controllers.CreateAccessToken)
+		account.DELETE("/tokens/:id", controllers.RevokeAccessToken)
+		account.POST("/mfa/totp", controllers.BeginTOTPEnrollment)
+		account.POST("/mfa/totp/confirm", controllers.ConfirmTOTPEnrollment)
+		account.DELETE("/mfa/totp", controllers.DisableTOTP)
+		account.POST("/mfa/recovery-codes", controllers.RegenerateRecoveryCodes)
+
+		posts := protected.Group("/posts",
+			middleware.RequireScope(models.ScopePostsWrite),
+			middleware.RequirePermission(models.PermissionWritePosts),
+		)
+		posts.POST("", controllers.CreatePost)
+		posts.PUT("/:id", controllers.UpdatePost)
+		posts.DELETE("/:id", controllers.DeletePost)
+		posts.POST("/:id/restore", controllers.RestorePost)
+		posts.DELETE("/:id/purge", controllers.PurgePost)
+		posts.GET("/:id/revisions", controllers.ListPostRevisions)
+		posts.GET("/:id/revisions/diff", controllers.DiffPostRevisions)
+		posts.GET("/:id/revisions/:rev", controllers.GetPostRevision)
+		posts.POST("/:id/revisions/:rev/restore", controllers.RestorePostRevision)
+		posts.GET("/:id/previews", controllers.ListPostPreviews)
+		posts.POST("/:id/previews", controllers.CreatePostPreview)
+		posts.DELETE("/:id/previews/:previewId", controllers.RevokePostPreview)
+		posts.GET("/:id/previews/:previewId/views", controllers.ListPostPreviewViews)
+		posts.GET("/:id/transitions", controllers.ListPostTransitions)
+		posts.POST("/:id/submit", controllers.SubmitPostForReview)
+		posts.POST("/:id/review",
+			middleware.RequirePermission(models.PermissionEditOthersPosts),
+			controllers.ReviewPost,
+		)
+
+		protected.GET("/review-queue",
+			middleware.RequireScope(models.ScopePostsWrite),
+			middleware.RequirePermission(models.PermissionEditOthersPosts),
+			controllers.ListReviewQueue,
+		)
+
+		comments := protected.Group("/comments",
+			middleware.RequireScope(models.ScopeCommentsWrite),
+		)
+		comments.PUT("/:id", controllers.UpdateComment)
+		comments.DELETE("/:id", controllers.DeleteComment)
+
+		moderation := protected.Group("/moderation",
+			middleware.RequireScope(models.ScopeCommentsModerate),
+			middleware.RequirePermission(models.PermissionWritePosts),
+		)
+		moderation.GET("/comments", controllers.ListModerationComments)
+		moderation.POST("/comments", controllers.ModerateComments)
+
+		manageTaxonomy := []gin.HandlerFunc{
+			middleware.RequireScope(models.ScopeTaxonomyWrite),
+			middleware.RequirePermission(models.PermissionManageTaxonomy),
+		}
+		categories := protected.Group("/categories", manageTaxonomy...)
+		categories.POST("", controllers.CreateCategory)
+		categories.PUT("/:id", controllers.UpdateCategory)
+		categories.DELETE("/:id", controllers.DeleteCategory)
+
+		tags := protected.Group("/tags", manageTaxonomy...)
+		tags.POST("", controllers.CreateTag)
+		tags.PUT("/:id", controllers.UpdateTag)
+		tags.DELETE("/:id", controllers.DeleteTag)
+
+		admin := protected.Group("/admin",
+			middleware.RequireSession(),
+			middleware.RequirePermission(models.PermissionManageUsers),
+		)
+		admin.GET("/users", controllers.ListUsers)
+		admin.GET("/users/:id", controllers.GetUser)
+		admin.PUT("/users/:id/role", controllers.UpdateUserRole)
+		admin.POST("/users/:id/suspend", controllers.SuspendUser)
+		admin.POST("/users/:id/unsuspend", controllers.UnsuspendUser)
+		admin.POST("/users/:id/unlock", controllers.UnlockUser)
+		admin.DELETE("/users/:id", controllers.DeleteUser)
 	}
 
 	api.GET("/posts", controllers.ListPosts)
 	api.GET("/posts/slug/:slug", controllers.GetPostBySlug)
 	api.GET("/posts/:id", controllers.GetPostByID)
+	api.POST("/posts/:id/unlock", controllers.UnlockPost)
 	api.GET("/posts/:id/comments", controllers.ListComments)
+	api.GET("/posts/:id/comments/:commentId/replies", controllers.ListCommentReplies)
 	api.POST("/posts/:id/comments", controllers.CreateComment)
 
+	api.GET("/search", controllers.SearchPosts)
+	api.GET("/preview", controllers.GetPostPreview)
+
+	api.GET("/users/:username", controllers.GetAuthorProfile)
+	api.GET("/users/:username/posts", controllers.ListAuthorPosts)
+
 	api.GET("/categories", controllers.ListCategories)
 	api.GET("/categories/:id/posts", controllers.ListPostsByCategory)
 	api.GET("/tags", controllers.ListTags)
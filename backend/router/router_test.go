@@ -0,0 +1,170 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gogogo/config"
+	"gogogo/global"
+	"gogogo/middleware"
+	"gogogo/models"
+	"gogogo/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupRouterTest(t *testing.T, trustedProxies []string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	config.AppConfig = &config.Config{}
+	config.AppConfig.Auth.JWTSecret = "test-secret"
+	config.AppConfig.App.TrustedProxies = trustedProxies
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.LoginThrottle{}, &models.PersonalAccessToken{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	global.Db = db
+
+	return SetupRouter()
+}
+
+// failLogin sends a wrong password for alice from remoteAddr, claiming to be
+// forwardedFor.
+func failLogin(t *testing.T, server *gin.Engine, remoteAddr, forwardedFor string) {
+	t.Helper()
+
+	request := httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(`{"username":"alice","password":"wrong"}`))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Forwarded-For", forwardedFor)
+	request.RemoteAddr = remoteAddr
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("login: status %d, body %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func ipThrottles(t *testing.T) map[string]int {
+	t.Helper()
+
+	var throttles []models.LoginThrottle
+	if err := global.Db.Where("throttle_key LIKE ?", "ip:%").Find(&throttles).Error; err != nil {
+		t.Fatalf("load throttles: %v", err)
+	}
+	failures := make(map[string]int, len(throttles))
+	for _, throttle := range throttles {
+		failures[throttle.Key] = throttle.Failures
+	}
+	return failures
+}
+
+func TestLoginThrottleIgnoresSpoofedForwardedFor(t *testing.T) {
+	server := setupRouterTest(t, nil)
+
+	failLogin(t, server, "192.0.2.10:40000", "203.0.113.1")
+	failLogin(t, server, "192.0.2.10:40001", "203.0.113.2")
+	failLogin(t, server, "192.0.2.10:40002", "203.0.113.3")
+
+	got := ipThrottles(t)
+	if len(got) != 1 || got["ip:192.0.2.10"] != 3 {
+		t.Fatalf("ip throttles = %v, want 3 failures on ip:192.0.2.10 only", got)
+	}
+}
+
+func TestLoginThrottleHonoursTrustedProxy(t *testing.T) {
+	server := setupRouterTest(t, []string{"192.0.2.0/24"})
+
+	failLogin(t, server, "192.0.2.10:40000", "203.0.113.1")
+	failLogin(t, server, "198.51.100.7:40000", "203.0.113.1")
+
+	got := ipThrottles(t)
+	if len(got) != 2 || got["ip:203.0.113.1"] != 1 || got["ip:198.51.100.7"] != 1 {
+		t.Fatalf("ip throttles = %v, want one failure each on the forwarded and the untrusted address", got)
+	}
+}
+
+func createRouterTestUser(t *testing.T, username, password string) models.User {
+	t.Helper()
+
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	email := username + "@example.com"
+	user := models.User{Username: username, Email: &email, Password: hash, Role: models.RoleAuthor}
+	if err := global.Db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+func serveRequest(server *gin.Engine, method, path, token, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestLoginLocksTheAccountAfterTooManyFailures(t *testing.T) {
+	server := setupRouterTest(t, nil)
+	config.AppConfig.Auth.LoginMaxAttempts = 3
+	createRouterTestUser(t, "alice", "correct horse")
+
+	// Failures from different addresses all count against the account.
+	failLogin(t, server, "192.0.2.10:40000", "")
+	failLogin(t, server, "192.0.2.11:40000", "")
+	failLogin(t, server, "192.0.2.12:40000", "")
+
+	recorder := serveRequest(server, http.MethodPost, "/api/auth/login", "", `{"username":"alice","password":"correct horse"}`)
+	if recorder.Code != http.StatusLocked {
+		t.Fatalf("login with the right password: status %d, want 423", recorder.Code)
+	}
+	if recorder.Header().Get("Retry-After") == "" {
+		t.Fatal("locked response has no Retry-After")
+	}
+}
+
+func TestPersonalAccessTokenNeedsItsScope(t *testing.T) {
+	server := setupRouterTest(t, nil)
+	user := createRouterTestUser(t, "alice", "correct horse")
+
+	token := middleware.PersonalAccessTokenPrefix + "test-token"
+	if err := global.Db.Create(&models.PersonalAccessToken{
+		UserID:    user.ID,
+		Name:      "deploy",
+		TokenHash: utils.HashToken(token),
+		Scopes:    models.ScopePostsWrite,
+	}).Error; err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+
+	if recorder := serveRequest(server, http.MethodGet, "/api/me", token, ""); recorder.Code != http.StatusOK {
+		t.Fatalf("GET /api/me: status %d, body %s", recorder.Code, recorder.Body.String())
+	}
+
+	recorder := serveRequest(server, http.MethodPut, "/api/comments/1", token, `{"body":"edited"}`)
+	if recorder.Code != http.StatusForbidden || !strings.Contains(recorder.Body.String(), models.ScopeCommentsWrite) {
+		t.Fatalf("comment edit without comments:write: status %d, body %s", recorder.Code, recorder.Body.String())
+	}
+
+	// Account management is closed to tokens whatever their scopes.
+	if recorder := serveRequest(server, http.MethodGet, "/api/me/sessions", token, ""); recorder.Code != http.StatusForbidden {
+		t.Fatalf("GET /api/me/sessions with a token: status %d, want 403", recorder.Code)
+	}
+}
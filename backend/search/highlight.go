@@ -0,0 +1,84 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// Highlight returns text as escaped HTML with the query terms wrapped in
+// <mark>. With maxRunes > 0 only a snippet of about that length around the
+// first match is returned, marked with ellipses where text was cut.
+func Highlight(text string, query string, maxRunes int) string {
+	terms := make(map[string]bool)
+	for _, term := range Terms(query) {
+		terms[term] = true
+	}
+
+	// Overlapping bigrams and adjacent words merge into one mark.
+	var marks [][2]int
+	for _, tok := range tokenize(text) {
+		if !terms[tok.text] {
+			continue
+		}
+		if n := len(marks); n > 0 && tok.start <= marks[n-1][1] {
+			if tok.end > marks[n-1][1] {
+				marks[n-1][1] = tok.end
+			}
+			continue
+		}
+		marks = append(marks, [2]int{tok.start, tok.end})
+	}
+
+	start, end := 0, len(text)
+	if maxRunes > 0 && utf8.RuneCountInString(text) > maxRunes {
+		focus := 0
+		if len(marks) > 0 {
+			focus = marks[0][0]
+		}
+		start = moveBack(text, focus, maxRunes/3)
+		end = moveForward(text, start, maxRunes)
+	}
+
+	var builder strings.Builder
+	if start > 0 {
+		builder.WriteString("…")
+	}
+	pos := start
+	for _, mark := range marks {
+		if mark[1] <= start || mark[0] >= end {
+			continue
+		}
+		markStart, markEnd := max(mark[0], start), min(mark[1], end)
+		builder.WriteString(html.EscapeString(text[pos:markStart]))
+		builder.WriteString("<mark>")
+		builder.WriteString(html.EscapeString(text[markStart:markEnd]))
+		builder.WriteString("</mark>")
+		pos = markEnd
+	}
+	builder.WriteString(html.EscapeString(text[pos:end]))
+	if end < len(text) {
+		builder.WriteString("…")
+	}
+	return builder.String()
+}
+
+// moveBack steps n runes back from offset, preferring to stop after a space.
+func moveBack(text string, offset int, n int) int {
+	for i := 0; i < n && offset > 0; i++ {
+		_, size := utf8.DecodeLastRuneInString(text[:offset])
+		offset -= size
+	}
+	if space := strings.IndexByte(text[offset:], ' '); offset > 0 && space >= 0 && space < 20 {
+		offset += space + 1
+	}
+	return offset
+}
+
+func moveForward(text string, offset int, n int) int {
+	for i := 0; i < n && offset < len(text); i++ {
+		_, size := utf8.DecodeRuneInString(text[offset:])
+		offset += size
+	}
+	return offset
+}
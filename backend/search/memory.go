@@ -0,0 +1,187 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+type field int
+
+const (
+	fieldTitle field = iota
+	fieldTags
+	fieldSummary
+	fieldContent
+	fieldCount
+)
+
+// fieldWeights make a match in the title count for more than one deep in the
+// content.
+var fieldWeights = [fieldCount]float64{3, 2.5, 1.5, 1}
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// MemoryEngine is an in-process inverted index scored with BM25F. It needs no
+// database support, which makes it suitable for tests and small sites, but
+// it has to be rebuilt on every start.
+type MemoryEngine struct {
+	mu       sync.RWMutex
+	postings map[string]map[uint]*[fieldCount]int
+	lengths  map[uint][fieldCount]int
+	terms    map[uint][]string
+	totals   [fieldCount]int
+}
+
+func NewMemoryEngine() *MemoryEngine {
+	return &MemoryEngine{
+		postings: make(map[string]map[uint]*[fieldCount]int),
+		lengths:  make(map[uint][fieldCount]int),
+		terms:    make(map[uint][]string),
+	}
+}
+
+func (e *MemoryEngine) Index(doc Document) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.remove(doc.ID)
+
+	texts := [fieldCount][]string{
+		fieldTitle:   {doc.Title},
+		fieldTags:    doc.Tags,
+		fieldSummary: {doc.Summary},
+		fieldContent: {doc.Content},
+	}
+
+	var lengths [fieldCount]int
+	var terms []string
+	for f := field(0); f < fieldCount; f++ {
+		for _, text := range texts[f] {
+			for _, tok := range tokenize(text) {
+				docs := e.postings[tok.text]
+				if docs == nil {
+					docs = make(map[uint]*[fieldCount]int)
+					e.postings[tok.text] = docs
+				}
+				counts := docs[doc.ID]
+				if counts == nil {
+					counts = new([fieldCount]int)
+					docs[doc.ID] = counts
+					terms = append(terms, tok.text)
+				}
+				counts[f]++
+				lengths[f]++
+			}
+		}
+		e.totals[f] += lengths[f]
+	}
+
+	e.lengths[doc.ID] = lengths
+	e.terms[doc.ID] = terms
+	return nil
+}
+
+func (e *MemoryEngine) Remove(id uint) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.remove(id)
+	return nil
+}
+
+func (e *MemoryEngine) remove(id uint) {
+	lengths, ok := e.lengths[id]
+	if !ok {
+		return
+	}
+
+	for _, term := range e.terms[id] {
+		delete(e.postings[term], id)
+		if len(e.postings[term]) == 0 {
+			delete(e.postings, term)
+		}
+	}
+	for f := field(0); f < fieldCount; f++ {
+		e.totals[f] -= lengths[f]
+	}
+	delete(e.lengths, id)
+	delete(e.terms, id)
+}
+
+// Search only returns documents containing every query term.
+func (e *MemoryEngine) Search(query string, limit int) ([]Hit, error) {
+	terms := Terms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	docCount := float64(len(e.lengths))
+	var avgLengths [fieldCount]float64
+	for f := field(0); f < fieldCount; f++ {
+		if docCount > 0 {
+			avgLengths[f] = float64(e.totals[f]) / docCount
+		}
+	}
+
+	scores := make(map[uint]float64)
+	for i, term := range terms {
+		docs := e.postings[term]
+		if len(docs) == 0 {
+			return nil, nil
+		}
+
+		df := float64(len(docs))
+		idf := math.Log(1 + (docCount-df+0.5)/(df+0.5))
+
+		for id, counts := range docs {
+			if _, ok := scores[id]; !ok && i > 0 {
+				continue // missed an earlier term
+			}
+
+			lengths := e.lengths[id]
+			weighted := 0.0
+			for f := field(0); f < fieldCount; f++ {
+				if counts[f] == 0 {
+					continue
+				}
+				norm := 1.0
+				if avgLengths[f] > 0 {
+					norm = 1 - bm25B + bm25B*float64(lengths[f])/avgLengths[f]
+				}
+				weighted += fieldWeights[f] * float64(counts[f]) / norm
+			}
+			scores[id] += idf * weighted * (bm25K1 + 1) / (weighted + bm25K1)
+		}
+
+		// Drop documents that did not contain this term.
+		if i > 0 {
+			for id := range scores {
+				if _, ok := docs[id]; !ok {
+					delete(scores, id)
+				}
+			}
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID > hits[j].ID
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
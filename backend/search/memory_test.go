@@ -0,0 +1,128 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func hitIDs(hits []Hit) []uint {
+	ids := make([]uint, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+	return ids
+}
+
+func newTestEngine(t *testing.T, docs ...Document) *MemoryEngine {
+	t.Helper()
+	engine := NewMemoryEngine()
+	for _, doc := range docs {
+		if err := engine.Index(doc); err != nil {
+			t.Fatalf("index %d: %v", doc.ID, err)
+		}
+	}
+	return engine
+}
+
+func search(t *testing.T, engine *MemoryEngine, query string, limit int) []uint {
+	t.Helper()
+	hits, err := engine.Search(query, limit)
+	if err != nil {
+		t.Fatalf("search %q: %v", query, err)
+	}
+	return hitIDs(hits)
+}
+
+func TestMemoryEngineWeighsFields(t *testing.T) {
+	// Every field holds one word, so only the field weights differ.
+	engine := newTestEngine(t,
+		Document{ID: 1, Title: "notes", Tags: []string{"misc"}, Summary: "misc", Content: "gardening"},
+		Document{ID: 2, Title: "gardening", Tags: []string{"misc"}, Summary: "misc", Content: "notes"},
+		Document{ID: 3, Title: "notes", Tags: []string{"gardening"}, Summary: "misc", Content: "notes"},
+		Document{ID: 4, Title: "notes", Tags: []string{"misc"}, Summary: "gardening", Content: "notes"},
+	)
+
+	// Title beats tags, tags beat the summary, the summary beats the content.
+	if got, want := search(t, engine, "gardening", 0), []uint{2, 3, 4, 1}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("ranking %v, want %v", got, want)
+	}
+}
+
+func TestMemoryEnginePrefersRareTerms(t *testing.T) {
+	engine := newTestEngine(t,
+		Document{ID: 1, Content: "common common"},
+		Document{ID: 2, Content: "common rare"},
+		Document{ID: 3, Content: "common"},
+	)
+
+	hits, err := engine.Search("common rare", 0)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(hits) != 1 || hits[0].ID != 2 {
+		t.Fatalf("hits %v, want only the document with both terms", hits)
+	}
+
+	// Shorter fields score higher for the same term frequency.
+	if got, want := search(t, engine, "common", 0), []uint{1, 3, 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("ranking %v, want %v", got, want)
+	}
+}
+
+func TestMemoryEngineRequiresEveryTerm(t *testing.T) {
+	engine := newTestEngine(t,
+		Document{ID: 1, Title: "搜索引擎", Content: "ranking"},
+		Document{ID: 2, Title: "搜索", Content: "ranking"},
+	)
+
+	if got := search(t, engine, "搜索引擎", 0); !reflect.DeepEqual(got, []uint{1}) {
+		t.Fatalf("hits %v, want [1]", got)
+	}
+	if got := search(t, engine, "ranking missing", 0); len(got) != 0 {
+		t.Fatalf("hits %v, want none", got)
+	}
+	if got := search(t, engine, "!!", 0); len(got) != 0 {
+		t.Fatalf("hits %v for a query without terms", got)
+	}
+}
+
+func TestMemoryEngineReindexAndRemove(t *testing.T) {
+	engine := newTestEngine(t,
+		Document{ID: 1, Title: "first draft"},
+		Document{ID: 2, Title: "second draft"},
+		Document{ID: 3, Title: "third draft"},
+	)
+
+	if err := engine.Index(Document{ID: 1, Title: "final version"}); err != nil {
+		t.Fatalf("reindex: %v", err)
+	}
+	if got := search(t, engine, "first", 0); len(got) != 0 {
+		t.Fatalf("old text still found: %v", got)
+	}
+	if got := search(t, engine, "final", 0); !reflect.DeepEqual(got, []uint{1}) {
+		t.Fatalf("new text: hits %v, want [1]", got)
+	}
+
+	if err := engine.Remove(2); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if got := search(t, engine, "draft", 0); !reflect.DeepEqual(got, []uint{3}) {
+		t.Fatalf("after remove: hits %v, want [3]", got)
+	}
+	if got := search(t, engine, "draft version", 1); len(got) != 0 {
+		t.Fatalf("hits %v, want none", got)
+	}
+}
+
+func TestMemoryEngineLimit(t *testing.T) {
+	engine := newTestEngine(t,
+		Document{ID: 1, Title: "go"},
+		Document{ID: 2, Title: "go"},
+		Document{ID: 3, Title: "go"},
+	)
+
+	// Ties go to the newest post.
+	if got := search(t, engine, "go", 2); !reflect.DeepEqual(got, []uint{3, 2}) {
+		t.Fatalf("hits %v, want [3 2]", got)
+	}
+}
@@ -0,0 +1,101 @@
+package search
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// mysqlDocument is a denormalised copy of a post, including its tag names,
+// so a single FULLTEXT index covers everything that is searchable.
+type mysqlDocument struct {
+	PostID  uint   `gorm:"primaryKey;autoIncrement:false"`
+	Title   string `gorm:"size:200;index:ft_post_search_title,class:FULLTEXT,option:WITH PARSER ngram;index:ft_post_search_all,class:FULLTEXT,option:WITH PARSER ngram"`
+	Summary string `gorm:"size:512;index:ft_post_search_all,class:FULLTEXT,option:WITH PARSER ngram"`
+	Content string `gorm:"type:longtext;index:ft_post_search_all,class:FULLTEXT,option:WITH PARSER ngram"`
+	Tags    string `gorm:"type:text;index:ft_post_search_all,class:FULLTEXT,option:WITH PARSER ngram"`
+}
+
+func (mysqlDocument) TableName() string {
+	return "post_search_documents"
+}
+
+// MySQLEngine uses InnoDB FULLTEXT indexes with the ngram parser, which also
+// tokenises Chinese. Title matches are weighted up.
+type MySQLEngine struct {
+	db *gorm.DB
+}
+
+// NewMySQLEngine creates the document table and its indexes if needed.
+func NewMySQLEngine(db *gorm.DB) (*MySQLEngine, error) {
+	if err := db.AutoMigrate(&mysqlDocument{}); err != nil {
+		return nil, err
+	}
+	return &MySQLEngine{db: db}, nil
+}
+
+// IsEmpty reports whether nothing has been indexed yet, e.g. right after the
+// table was created for an existing site.
+func (e *MySQLEngine) IsEmpty() (bool, error) {
+	var count int64
+	err := e.db.Model(&mysqlDocument{}).Limit(1).Count(&count).Error
+	return count == 0, err
+}
+
+func (e *MySQLEngine) Index(doc Document) error {
+	return e.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&mysqlDocument{
+		PostID:  doc.ID,
+		Title:   doc.Title,
+		Summary: doc.Summary,
+		Content: doc.Content,
+		Tags:    strings.Join(doc.Tags, " "),
+	}).Error
+}
+
+func (e *MySQLEngine) Remove(id uint) error {
+	return e.db.Delete(&mysqlDocument{}, "post_id = ?", id).Error
+}
+
+// Search requires every word of the query, like MemoryEngine. Each word is
+// searched as a phrase, which the ngram parser turns into consecutive
+// n-grams.
+func (e *MySQLEngine) Search(query string, limit int) ([]Hit, error) {
+	against := booleanQuery(query)
+	if against == "" {
+		return nil, nil
+	}
+
+	if limit <= 0 {
+		limit = -1
+	}
+
+	var hits []Hit
+	err := e.db.Model(&mysqlDocument{}).
+		Select("post_id AS id, "+
+			"MATCH(title) AGAINST(? IN BOOLEAN MODE) * 2 + "+
+			"MATCH(title, summary, content, tags) AGAINST(? IN BOOLEAN MODE) AS score", against, against).
+		Where("MATCH(title, summary, content, tags) AGAINST(? IN BOOLEAN MODE)", against).
+		Order("score DESC, post_id DESC").
+		Limit(limit).
+		Scan(&hits).Error
+	return hits, err
+}
+
+// booleanQuery turns user input into +"word" +"word", dropping characters
+// that are operators in boolean mode.
+func booleanQuery(query string) string {
+	var parts []string
+	for _, word := range strings.Fields(query) {
+		word = strings.Map(func(r rune) rune {
+			if strings.ContainsRune(`+-<>()~*"@`, r) {
+				return -1
+			}
+			return r
+		}, word)
+		if word != "" {
+			parts = append(parts, `+"`+word+`"`)
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,23 @@
+package search
+
+import "testing"
+
+func TestBooleanQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"golang", `+"golang"`},
+		{"go  generics", `+"go" +"generics"`},
+		{`-drop +"table" (x)~*`, `+"drop" +"table" +"x"`},
+		{"中文 搜索", `+"中文" +"搜索"`},
+		{`+-<>()~*"@`, ``},
+		{"", ``},
+	}
+
+	for _, tt := range tests {
+		if got := booleanQuery(tt.query); got != tt.want {
+			t.Errorf("booleanQuery(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package search
+
+import (
+	"gogogo/markdown"
+	"gogogo/models"
+
+	"gorm.io/gorm"
+)
+
+// PostDocument returns the post's searchable text. Tags must be loaded. The
+// body of a password-protected post is left out so searches cannot probe it.
+func PostDocument(post models.Post) Document {
+	tags := make([]string, 0, len(post.Tags))
+	for _, tag := range post.Tags {
+		tags = append(tags, tag.Name)
+	}
+	content := ""
+	if post.Visibility != models.PostVisibilityPassword {
+		content = markdown.PlainText(post.ContentHTML)
+	}
+	return Document{
+		ID:      post.ID,
+		Title:   post.Title,
+		Summary: post.Summary,
+		Content: content,
+		Tags:    tags,
+	}
+}
+
+// IndexAllPosts feeds every post in db into engine and returns how many there
+// were.
+func IndexAllPosts(db *gorm.DB, engine Engine) (int, error) {
+	var posts []models.Post
+	indexed := 0
+	err := db.Preload("Tags").
+		FindInBatches(&posts, 100, func(tx *gorm.DB, batch int) error {
+			for i := range posts {
+				if err := engine.Index(PostDocument(posts[i])); err != nil {
+					return err
+				}
+			}
+			indexed += len(posts)
+			return nil
+		}).Error
+	return indexed, err
+}
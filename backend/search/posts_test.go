@@ -0,0 +1,30 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+
+	"gogogo/models"
+)
+
+func TestPostDocumentHidesPasswordProtectedContent(t *testing.T) {
+	post := models.Post{
+		Title:       "Secret",
+		Summary:     "teaser",
+		ContentHTML: "<p>hidden <strong>body</strong></p>",
+		Visibility:  models.PostVisibilityPublic,
+		Tags:        []models.Tag{{Name: "go"}, {Name: "search"}},
+	}
+	post.ID = 7
+
+	doc := PostDocument(post)
+	want := Document{ID: 7, Title: "Secret", Summary: "teaser", Content: "hidden body", Tags: []string{"go", "search"}}
+	if !reflect.DeepEqual(doc, want) {
+		t.Fatalf("document %+v, want %+v", doc, want)
+	}
+
+	post.Visibility = models.PostVisibilityPassword
+	if doc := PostDocument(post); doc.Content != "" || doc.Summary != "teaser" {
+		t.Fatalf("password post indexed as %+v", doc)
+	}
+}
@@ -0,0 +1,102 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Document is the searchable text of one post. Engines index every post;
+// visibility is left to the caller, so status changes need no reindexing.
+type Document struct {
+	ID      uint
+	Title   string
+	Summary string
+	Content string
+	Tags    []string
+}
+
+type Hit struct {
+	ID    uint
+	Score float64
+}
+
+// Engine ranks documents for a query. Search returns at most limit hits,
+// best first.
+type Engine interface {
+	Index(doc Document) error
+	Remove(id uint) error
+	Search(query string, limit int) ([]Hit, error)
+}
+
+type token struct {
+	text  string
+	start int
+	end   int
+}
+
+// tokenize lowercases text and splits it into words. Runs of CJK characters
+// become overlapping bigrams, like MySQL's ngram parser, because they are
+// not separated by spaces.
+func tokenize(text string) []token {
+	var tokens []token
+	var run []int // byte offsets of the runes in the current run
+	runCJK := false
+
+	flush := func(end int) {
+		if len(run) == 0 {
+			return
+		}
+		if !runCJK {
+			tokens = append(tokens, token{text: strings.ToLower(text[run[0]:end]), start: run[0], end: end})
+		} else if len(run) == 1 {
+			tokens = append(tokens, token{text: text[run[0]:end], start: run[0], end: end})
+		} else {
+			for i := 0; i+1 < len(run); i++ {
+				stop := end
+				if i+2 < len(run) {
+					stop = run[i+2]
+				}
+				tokens = append(tokens, token{text: text[run[i]:stop], start: run[i], end: stop})
+			}
+		}
+		run = run[:0]
+	}
+
+	for offset, r := range text {
+		switch {
+		case isCJK(r):
+			if !runCJK {
+				flush(offset)
+				runCJK = true
+			}
+			run = append(run, offset)
+		case unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.Is(unicode.Mn, r):
+			if runCJK {
+				flush(offset)
+				runCJK = false
+			}
+			run = append(run, offset)
+		default:
+			flush(offset)
+		}
+	}
+	flush(len(text))
+	return tokens
+}
+
+// Terms returns the distinct index terms of a query in order.
+func Terms(query string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, tok := range tokenize(query) {
+		if !seen[tok.text] {
+			seen[tok.text] = true
+			terms = append(terms, tok.text)
+		}
+	}
+	return terms
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
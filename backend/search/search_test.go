@@ -0,0 +1,27 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"Hello, World! hello", []string{"hello", "world"}},
+		{"Go1.22 release", []string{"go1", "22", "release"}},
+		{"中文", []string{"中文"}},
+		{"搜索引擎", []string{"搜索", "索引", "引擎"}},
+		{"学Go语言", []string{"学", "go", "语言"}},
+		{"café naïve", []string{"café", "naïve"}},
+		{"  ...  ", nil},
+	}
+
+	for _, tt := range tests {
+		if got := Terms(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Terms(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package spam
+
+import (
+	"math"
+	"sort"
+
+	"gogogo/search"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Labels a comment can be trained as.
+const (
+	LabelSpam = "spam"
+	LabelHam  = "ham"
+)
+
+const (
+	// minTrainingDocs is how many comments of each label the classifier needs
+	// before its opinion counts.
+	minTrainingDocs = 5
+	// interestingTokens is how many of the most telling tokens are combined.
+	interestingTokens = 15
+	// maxFeatures caps what is learned from a single long comment.
+	maxFeatures   = 200
+	maxTokenBytes = 64
+)
+
+// tokenCount records how many spam and ham comments contained a token.
+type tokenCount struct {
+	Token string `gorm:"primaryKey;size:64"`
+	Spam  int    `gorm:"not null;default:0"`
+	Ham   int    `gorm:"not null;default:0"`
+}
+
+func (tokenCount) TableName() string {
+	return "spam_tokens"
+}
+
+// corpus is a single row holding how many comments were trained per label.
+type corpus struct {
+	ID   uint `gorm:"primaryKey;autoIncrement:false"`
+	Spam int  `gorm:"not null;default:0"`
+	Ham  int  `gorm:"not null;default:0"`
+}
+
+func (corpus) TableName() string {
+	return "spam_corpus"
+}
+
+const corpusID = 1
+
+// Train counts a comment body as spam or ham. tx may be a transaction so the
+// counts change together with the moderation decision.
+func (f *Filter) Train(tx *gorm.DB, body string, label string) error {
+	return learn(tx, body, label, 1)
+}
+
+// Forget undoes an earlier Train with the same body and label, used when a
+// moderator changes their mind.
+func (f *Filter) Forget(tx *gorm.DB, body string, label string) error {
+	return learn(tx, body, label, -1)
+}
+
+func learn(tx *gorm.DB, body string, label string, delta int) error {
+	if label != LabelSpam && label != LabelHam {
+		return nil
+	}
+	increment := clause.Assignments(map[string]interface{}{
+		label: gorm.Expr(label+" + ?", delta),
+	})
+
+	row := corpus{ID: corpusID}
+	if label == LabelSpam {
+		row.Spam = max(delta, 0)
+	} else {
+		row.Ham = max(delta, 0)
+	}
+	if err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: increment,
+	}).Create(&row).Error; err != nil {
+		return err
+	}
+
+	tokens := Features(body)
+	if len(tokens) == 0 {
+		return nil
+	}
+	rows := make([]tokenCount, 0, len(tokens))
+	for _, token := range tokens {
+		row := tokenCount{Token: token}
+		if label == LabelSpam {
+			row.Spam = max(delta, 0)
+		} else {
+			row.Ham = max(delta, 0)
+		}
+		rows = append(rows, row)
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "token"}},
+		DoUpdates: increment,
+	}).CreateInBatches(rows, 100).Error
+}
+
+// classify returns the probability that a body is spam, combining the most
+// telling tokens with Robinson's method. trained is false until both labels
+// have enough examples.
+func (f *Filter) classify(body string, hosts []string) (probability float64, trained bool, err error) {
+	var totals corpus
+	if err := f.db.Where("id = ?", corpusID).Limit(1).Find(&totals).Error; err != nil {
+		return 0, false, err
+	}
+	if totals.Spam < minTrainingDocs || totals.Ham < minTrainingDocs {
+		return 0, false, nil
+	}
+
+	tokens := features(body, hosts)
+	if len(tokens) == 0 {
+		return 0.5, true, nil
+	}
+	var counts []tokenCount
+	if err := f.db.Where("token IN ?", tokens).Find(&counts).Error; err != nil {
+		return 0, false, err
+	}
+
+	scores := make([]float64, 0, len(counts))
+	for _, count := range counts {
+		spamRate := float64(count.Spam) / float64(totals.Spam)
+		hamRate := float64(count.Ham) / float64(totals.Ham)
+		if spamRate+hamRate == 0 {
+			continue
+		}
+		// Tokens seen only a few times are pulled towards 0.5.
+		seen := float64(count.Spam + count.Ham)
+		p := (0.5 + seen*spamRate/(spamRate+hamRate)) / (1 + seen)
+		scores = append(scores, math.Min(math.Max(p, 0.01), 0.99))
+	}
+	if len(scores) == 0 {
+		return 0.5, true, nil
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		return math.Abs(scores[i]-0.5) > math.Abs(scores[j]-0.5)
+	})
+	if len(scores) > interestingTokens {
+		scores = scores[:interestingTokens]
+	}
+
+	var logSpam, logHam float64
+	for _, p := range scores {
+		logSpam += math.Log(p)
+		logHam += math.Log(1 - p)
+	}
+	return 1 / (1 + math.Exp(logHam-logSpam)), true, nil
+}
+
+func features(body string, hosts []string) []string {
+	seen := make(map[string]bool)
+	var tokens []string
+	addToken := func(token string) {
+		if len(token) > maxTokenBytes || seen[token] || len(tokens) >= maxFeatures {
+			return
+		}
+		seen[token] = true
+		tokens = append(tokens, token)
+	}
+	for _, host := range hosts {
+		addToken("domain:" + host)
+	}
+	for _, term := range search.Terms(body) {
+		addToken(term)
+	}
+	return tokens
+}
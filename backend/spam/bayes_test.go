@@ -0,0 +1,109 @@
+package spam
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFeatures(t *testing.T) {
+	got := Features("Cheap pills cheap PILLS at https://www.Pills.example/buy")
+	want := []string{"domain:pills.example", "cheap", "pills", "at", "https", "www", "example", "buy"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Features = %q, want %q", got, want)
+	}
+}
+
+func TestFeaturesSkipsLongTokensAndCapsTheCount(t *testing.T) {
+	words := make([]string, 0, maxFeatures+50)
+	for i := 0; i < maxFeatures+50; i++ {
+		words = append(words, "w"+strings.Repeat("x", i%5)+string(rune('a'+i%26))+string(rune('a'+i/26)))
+	}
+	words = append([]string{strings.Repeat("y", maxTokenBytes+1)}, words...)
+
+	got := Features(strings.Join(words, " "))
+	if len(got) != maxFeatures {
+		t.Fatalf("%d features, want %d", len(got), maxFeatures)
+	}
+	for _, token := range got {
+		if len(token) > maxTokenBytes {
+			t.Fatalf("kept a %d byte token", len(token))
+		}
+	}
+}
+
+func trainTestFilter(t *testing.T, filter *Filter, label string, bodies ...string) {
+	t.Helper()
+	for _, body := range bodies {
+		if err := filter.Train(filter.db, body, label); err != nil {
+			t.Fatalf("train %s: %v", label, err)
+		}
+	}
+}
+
+func classifierScore(t *testing.T, filter *Filter, body string) (float64, bool) {
+	t.Helper()
+	probability, trained, err := filter.classify(body, linkHosts(linkPattern.FindAllString(body, -1)))
+	if err != nil {
+		t.Fatalf("classify: %v", err)
+	}
+	return probability, trained
+}
+
+func TestClassifierLearnsFromModeration(t *testing.T) {
+	filter := newTestFilter(t, Config{})
+	spamBodies := []string{
+		"cheap pills at http://pills.example",
+		"buy cheap pills now",
+		"cheap watches and pills",
+		"pills pills pills http://pills.example",
+		"discount pills, cheap",
+	}
+	hamBodies := []string{
+		"great article about goroutines",
+		"the goroutines section was clear",
+		"thanks for the article",
+		"how do channels compare to mutexes",
+		"nice write up on channels",
+	}
+
+	trainTestFilter(t, filter, LabelSpam, spamBodies[:4]...)
+	trainTestFilter(t, filter, LabelHam, hamBodies...)
+	if _, trained := classifierScore(t, filter, "cheap pills"); trained {
+		t.Fatal("classifier counted with only four spam examples")
+	}
+
+	trainTestFilter(t, filter, LabelSpam, spamBodies[4])
+	if p, trained := classifierScore(t, filter, "cheap pills here http://pills.example"); !trained || p < 0.9 {
+		t.Fatalf("spam probability %v (trained %v), want above 0.9", p, trained)
+	}
+	if p, _ := classifierScore(t, filter, "a question about goroutines and channels"); p > 0.1 {
+		t.Fatalf("ham probability %v, want below 0.1", p)
+	}
+	if p, _ := classifierScore(t, filter, "unseen words only"); p != 0.5 {
+		t.Fatalf("unknown tokens give %v, want 0.5", p)
+	}
+
+	// Check adds the classifier's opinion to the rule score.
+	result, err := filter.Check(Comment{Body: "cheap pills", UserAgent: "curl"})
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if result.Score <= 0 || len(result.Reasons) != 1 || !strings.HasPrefix(result.Reasons[0], "classifier ") {
+		t.Fatalf("score %v %q, want only a positive classifier score", result.Score, result.Reasons)
+	}
+
+	// Forgetting a sample undoes it, so the classifier drops back below the
+	// training minimum.
+	if err := filter.Forget(filter.db, spamBodies[4], LabelSpam); err != nil {
+		t.Fatalf("forget: %v", err)
+	}
+	if _, trained := classifierScore(t, filter, "cheap pills"); trained {
+		t.Fatal("classifier still counts after forgetting a spam example")
+	}
+	var discount tokenCount
+	filter.db.Where("token = ?", "discount").First(&discount)
+	if discount.Spam != 0 {
+		t.Fatalf("discount seen in %d spam comments after forgetting, want 0", discount.Spam)
+	}
+}
@@ -0,0 +1,215 @@
+package spam
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type Verdict int
+
+const (
+	Allow Verdict = iota
+	Hold
+	Reject
+)
+
+// Weights of the individual signals. A comment's score is their sum, so the
+// hold and reject thresholds in Config are expressed in the same units.
+const (
+	weightExtraLink     = 1.0
+	maxExtraLinkScore   = 3.0
+	weightLinkDensity   = 1.5
+	weightBlockedWord   = 2.0
+	weightBlockedDomain = 4.0
+	weightBlockedIP     = 10.0
+	weightDuplicate     = 2.5
+	weightTooFast       = 2.5
+	weightNoUserAgent   = 1.0
+	// weightClassifier scales the classifier's spam probability, centred on
+	// 0.5, so confident ham lowers the score as much as spam raises it.
+	weightClassifier = 6.0
+)
+
+type Config struct {
+	HoldScore      float64
+	RejectScore    float64
+	MaxLinks       int
+	BlockedWords   []string
+	BlockedDomains []string
+	BlockedIPs     []string
+	// MaxPerMinute is how many recent comments from one IP count as too fast;
+	// 0 disables the check.
+	MaxPerMinute int
+}
+
+// Comment is what the filter looks at. Duplicates and RecentFromIP are
+// counted by the caller, which owns the comments table.
+type Comment struct {
+	Body      string
+	IP        string
+	UserAgent string
+	// Duplicates is how many recent comments had the same normalised body.
+	Duplicates int
+	// RecentFromIP is how many comments this IP posted in the last minute.
+	RecentFromIP int
+}
+
+type Result struct {
+	Score   float64
+	Reasons []string
+	Verdict Verdict
+}
+
+// Filter scores comments with fixed rules plus a naive Bayes classifier
+// trained from moderation decisions.
+type Filter struct {
+	db          *gorm.DB
+	config      Config
+	blockedNets []*net.IPNet
+	words       []string
+	domains     []string
+}
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"')\]]+`)
+
+// NewFilter creates the classifier tables if needed. Blocked IPs may be
+// single addresses or CIDR ranges.
+func NewFilter(db *gorm.DB, config Config) (*Filter, error) {
+	if err := db.AutoMigrate(&tokenCount{}, &corpus{}); err != nil {
+		return nil, err
+	}
+
+	filter := &Filter{db: db, config: config}
+	for _, entry := range config.BlockedIPs {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		cidr := entry
+		if !strings.Contains(cidr, "/") {
+			if strings.Contains(cidr, ":") {
+				cidr += "/128"
+			} else {
+				cidr += "/32"
+			}
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid blocked IP %q: %w", entry, err)
+		}
+		filter.blockedNets = append(filter.blockedNets, network)
+	}
+	for _, word := range config.BlockedWords {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			filter.words = append(filter.words, word)
+		}
+	}
+	for _, domain := range config.BlockedDomains {
+		if domain = normaliseHost(domain); domain != "" {
+			filter.domains = append(filter.domains, domain)
+		}
+	}
+	return filter, nil
+}
+
+// Check scores a comment and decides whether it may go straight through.
+func (f *Filter) Check(comment Comment) (Result, error) {
+	var result Result
+	add := func(score float64, reason string) {
+		result.Score += score
+		result.Reasons = append(result.Reasons, reason)
+	}
+
+	links := linkPattern.FindAllString(comment.Body, -1)
+	if extra := len(links) - f.config.MaxLinks; extra > 0 {
+		add(min(float64(extra)*weightExtraLink, maxExtraLinkScore), fmt.Sprintf("%d links", len(links)))
+	}
+	if words := len(strings.Fields(comment.Body)); len(links) > 0 && len(links)*3 >= words {
+		add(weightLinkDensity, "mostly links")
+	}
+
+	body := strings.ToLower(comment.Body)
+	for _, word := range f.words {
+		if strings.Contains(body, word) {
+			add(weightBlockedWord, fmt.Sprintf("blocked word %q", word))
+		}
+	}
+
+	hosts := linkHosts(links)
+	for _, domain := range f.domains {
+		for _, host := range hosts {
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				add(weightBlockedDomain, "blocked domain "+domain)
+				break
+			}
+		}
+	}
+
+	if ip := net.ParseIP(comment.IP); ip != nil {
+		for _, network := range f.blockedNets {
+			if network.Contains(ip) {
+				add(weightBlockedIP, "blocked IP")
+				break
+			}
+		}
+	}
+
+	if comment.Duplicates > 0 {
+		add(weightDuplicate, "duplicate of a recent comment")
+	}
+	if f.config.MaxPerMinute > 0 && comment.RecentFromIP >= f.config.MaxPerMinute {
+		add(weightTooFast, "too many comments from this IP")
+	}
+	if strings.TrimSpace(comment.UserAgent) == "" {
+		add(weightNoUserAgent, "no user agent")
+	}
+
+	probability, trained, err := f.classify(comment.Body, hosts)
+	if err != nil {
+		return result, err
+	}
+	if trained {
+		add((probability-0.5)*weightClassifier, fmt.Sprintf("classifier %.2f", probability))
+	}
+
+	switch {
+	case f.config.RejectScore > 0 && result.Score >= f.config.RejectScore:
+		result.Verdict = Reject
+	case f.config.HoldScore > 0 && result.Score >= f.config.HoldScore:
+		result.Verdict = Hold
+	}
+	return result, nil
+}
+
+// Features returns what the classifier learns from a comment body: its words
+// and the domains it links to.
+func Features(body string) []string {
+	return features(body, linkHosts(linkPattern.FindAllString(body, -1)))
+}
+
+func linkHosts(links []string) []string {
+	hosts := make([]string, 0, len(links))
+	for _, link := range links {
+		if !strings.Contains(link, "://") {
+			link = "http://" + link
+		}
+		parsed, err := url.Parse(link)
+		if err != nil {
+			continue
+		}
+		if host := normaliseHost(parsed.Hostname()); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+func normaliseHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	return strings.TrimPrefix(host, "www.")
+}
@@ -0,0 +1,158 @@
+package spam
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	return db
+}
+
+func newTestFilter(t *testing.T, config Config) *Filter {
+	t.Helper()
+
+	filter, err := NewFilter(openTestDB(t), config)
+	if err != nil {
+		t.Fatalf("NewFilter: %v", err)
+	}
+	return filter
+}
+
+func TestCheckScoresRules(t *testing.T) {
+	filter := newTestFilter(t, Config{
+		MaxLinks:       1,
+		BlockedWords:   []string{" Casino "},
+		BlockedDomains: []string{"www.Spam.example"},
+		BlockedIPs:     []string{"203.0.113.0/24", "2001:db8::1"},
+		MaxPerMinute:   3,
+	})
+
+	tests := []struct {
+		name    string
+		comment Comment
+		score   float64
+		reasons []string
+	}{
+		{
+			name:    "clean",
+			comment: Comment{Body: "Thanks, this helped a lot", IP: "192.0.2.1", UserAgent: "curl"},
+		},
+		{
+			name:    "extra links",
+			comment: Comment{Body: "see https://a.example and www.b.example and https://c.example for the details you asked about earlier today", UserAgent: "curl"},
+			score:   2 * weightExtraLink,
+			reasons: []string{"3 links"},
+		},
+		{
+			name:    "extra links are capped",
+			comment: Comment{Body: "a http://1.example b http://2.example c http://3.example d http://4.example e http://5.example f http://6.example g h i j k l m n o p q r s t", UserAgent: "curl"},
+			score:   maxExtraLinkScore,
+			reasons: []string{"6 links"},
+		},
+		{
+			name:    "mostly links",
+			comment: Comment{Body: "look https://a.example", UserAgent: "curl"},
+			score:   weightLinkDensity,
+			reasons: []string{"mostly links"},
+		},
+		{
+			name:    "blocked word ignores case",
+			comment: Comment{Body: "Best CASINO bonuses", UserAgent: "curl"},
+			score:   weightBlockedWord,
+			reasons: []string{`blocked word "casino"`},
+		},
+		{
+			name:    "blocked domain matches subdomains",
+			comment: Comment{Body: "more at http://shop.spam.example/offer in my profile, it has everything", UserAgent: "curl"},
+			score:   weightBlockedDomain,
+			reasons: []string{"blocked domain spam.example"},
+		},
+		{
+			name:    "lookalike domain is not blocked",
+			comment: Comment{Body: "more at http://notspam.example/offer in my profile, it has everything", UserAgent: "curl"},
+		},
+		{
+			name:    "blocked CIDR",
+			comment: Comment{Body: "hello", IP: "203.0.113.77", UserAgent: "curl"},
+			score:   weightBlockedIP,
+			reasons: []string{"blocked IP"},
+		},
+		{
+			name:    "blocked single IPv6 address",
+			comment: Comment{Body: "hello", IP: "2001:db8::1", UserAgent: "curl"},
+			score:   weightBlockedIP,
+			reasons: []string{"blocked IP"},
+		},
+		{
+			name:    "duplicate, flooding and no user agent",
+			comment: Comment{Body: "hello", Duplicates: 2, RecentFromIP: 3},
+			score:   weightDuplicate + weightTooFast + weightNoUserAgent,
+			reasons: []string{"duplicate of a recent comment", "too many comments from this IP", "no user agent"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := filter.Check(tt.comment)
+			if err != nil {
+				t.Fatalf("Check: %v", err)
+			}
+			if math.Abs(result.Score-tt.score) > 1e-9 || !reflect.DeepEqual(result.Reasons, tt.reasons) {
+				t.Fatalf("score %v %q, want %v %q", result.Score, result.Reasons, tt.score, tt.reasons)
+			}
+			if result.Verdict != Allow {
+				t.Fatalf("verdict %v without thresholds, want Allow", result.Verdict)
+			}
+		})
+	}
+}
+
+func TestCheckVerdicts(t *testing.T) {
+	filter := newTestFilter(t, Config{
+		HoldScore:    3,
+		RejectScore:  6,
+		BlockedWords: []string{"casino"},
+		BlockedIPs:   []string{"203.0.113.5"},
+	})
+
+	tests := []struct {
+		name    string
+		comment Comment
+		want    Verdict
+	}{
+		{"below hold", Comment{Body: "casino", UserAgent: "curl"}, Allow},
+		{"at hold", Comment{Body: "casino", Duplicates: 1}, Hold},
+		{"at reject", Comment{Body: "hello", IP: "203.0.113.5", UserAgent: "curl"}, Reject},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := filter.Check(tt.comment)
+			if err != nil {
+				t.Fatalf("Check: %v", err)
+			}
+			if result.Verdict != tt.want {
+				t.Fatalf("verdict %v with score %v, want %v", result.Verdict, result.Score, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFilterRejectsInvalidBlockedIPs(t *testing.T) {
+	if _, err := NewFilter(openTestDB(t), Config{BlockedIPs: []string{"203.0.113.300"}}); err == nil {
+		t.Fatal("NewFilter accepted an invalid blocked IP")
+	}
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gogogo/config"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// ActionClaims describe a signed token that authorises one specific action,
+// such as resetting a password. The purpose travels as the audience so these
+// tokens can never be mistaken for access tokens.
+type ActionClaims struct {
+	jwt.StandardClaims
+}
+
+func (c *ActionClaims) SubjectID() (uint, error) {
+	id, err := strconv.ParseUint(c.Subject, 10, 64)
+	return uint(id), err
+}
+
+func GenerateActionToken(purpose string, subjectID uint, tokenID string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := &ActionClaims{
+		StandardClaims: jwt.StandardClaims{
+			Audience:  purpose,
+			ExpiresAt: now.Add(ttl).Unix(),
+			Id:        tokenID,
+			IssuedAt:  now.Unix(),
+			Issuer:    config.AppConfig.App.Name,
+			Subject:   strconv.FormatUint(uint64(subjectID), 10),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(config.AppConfig.Auth.JWTSecret))
+}
+
+func ValidateActionToken(tokenString string, purpose string) (*ActionClaims, error) {
+	parsed, err := jwt.ParseWithClaims(tokenString, &ActionClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(config.AppConfig.Auth.JWTSecret), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := parsed.Claims.(*ActionClaims)
+	if !ok || !parsed.Valid || !claims.VerifyAudience(purpose, true) {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// MaxDiffCells caps the LCS table DiffLines builds for the changed middle of
+// the two texts, about 16 MB at four bytes a cell.
+const MaxDiffCells = 4_000_000
+
+// ErrDiffTooLarge is returned instead of a diff whose LCS table would exceed
+// MaxDiffCells.
+var ErrDiffTooLarge = errors.New("texts differ in too many lines to diff")
+
+// DiffLine is one line of a line-level diff. OldLine and NewLine are 1-based
+// and zero when the line does not exist on that side.
+type DiffLine struct {
+	Op      string `json:"op"`
+	Text    string `json:"text"`
+	OldLine int    `json:"oldLine,omitempty"`
+	NewLine int    `json:"newLine,omitempty"`
+}
+
+// DiffLines computes a line diff from the longest common subsequence of the
+// two texts. Common leading and trailing lines are skipped before the
+// quadratic step, which keeps typical edits cheap; rewrites too large for
+// that step fail with ErrDiffTooLarge.
+func DiffLines(oldText string, newText string) ([]DiffLine, error) {
+	oldLines := splitLines(oldText)
+	newLines := splitLines(newText)
+
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+
+	changedOld := len(oldLines) - prefix - suffix
+	changedNew := len(newLines) - prefix - suffix
+	if (changedOld+1)*(changedNew+1) > MaxDiffCells {
+		return nil, ErrDiffTooLarge
+	}
+
+	result := make([]DiffLine, 0, len(oldLines)+len(newLines)-prefix-suffix)
+	for i := 0; i < prefix; i++ {
+		result = append(result, DiffLine{Op: DiffEqual, Text: oldLines[i], OldLine: i + 1, NewLine: i + 1})
+	}
+
+	a := oldLines[prefix : len(oldLines)-suffix]
+	b := newLines[prefix : len(newLines)-suffix]
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			result = append(result, DiffLine{Op: DiffEqual, Text: a[i], OldLine: prefix + i + 1, NewLine: prefix + j + 1})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			result = append(result, DiffLine{Op: DiffDelete, Text: a[i], OldLine: prefix + i + 1})
+			i++
+		default:
+			result = append(result, DiffLine{Op: DiffInsert, Text: b[j], NewLine: prefix + j + 1})
+			j++
+		}
+	}
+
+	for k := 0; k < suffix; k++ {
+		oldIndex := len(oldLines) - suffix + k
+		newIndex := len(newLines) - suffix + k
+		result = append(result, DiffLine{Op: DiffEqual, Text: oldLines[oldIndex], OldLine: oldIndex + 1, NewLine: newIndex + 1})
+	}
+
+	return result, nil
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
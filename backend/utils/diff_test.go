@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     []DiffLine
+	}{
+		{
+			name: "identical",
+			old:  "a\nb\n",
+			new:  "a\r\nb",
+			want: []DiffLine{
+				{Op: DiffEqual, Text: "a", OldLine: 1, NewLine: 1},
+				{Op: DiffEqual, Text: "b", OldLine: 2, NewLine: 2},
+			},
+		},
+		{
+			name: "from empty",
+			old:  "",
+			new:  "a\nb",
+			want: []DiffLine{
+				{Op: DiffInsert, Text: "a", NewLine: 1},
+				{Op: DiffInsert, Text: "b", NewLine: 2},
+			},
+		},
+		{
+			name: "changed middle line",
+			old:  "a\nb\nc",
+			new:  "a\nx\nc",
+			want: []DiffLine{
+				{Op: DiffEqual, Text: "a", OldLine: 1, NewLine: 1},
+				{Op: DiffDelete, Text: "b", OldLine: 2},
+				{Op: DiffInsert, Text: "x", NewLine: 2},
+				{Op: DiffEqual, Text: "c", OldLine: 3, NewLine: 3},
+			},
+		},
+		{
+			name: "moved line keeps the longest common run",
+			old:  "a\nb\nc\nd",
+			new:  "b\nc\nd\na",
+			want: []DiffLine{
+				{Op: DiffDelete, Text: "a", OldLine: 1},
+				{Op: DiffEqual, Text: "b", OldLine: 2, NewLine: 1},
+				{Op: DiffEqual, Text: "c", OldLine: 3, NewLine: 2},
+				{Op: DiffEqual, Text: "d", OldLine: 4, NewLine: 3},
+				{Op: DiffInsert, Text: "a", NewLine: 4},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DiffLines(tt.old, tt.new)
+			if err != nil {
+				t.Fatalf("DiffLines: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("DiffLines(%q, %q)\n got %+v\nwant %+v", tt.old, tt.new, got, tt.want)
+			}
+		})
+	}
+}
+
+func numberedLines(prefix string, n int) string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = prefix + strconv.Itoa(i)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestDiffLinesRefusesHugeRewrites(t *testing.T) {
+	// 2500 x 2500 changed lines need a 6.25M cell table.
+	_, err := DiffLines(numberedLines("old", 2500), numberedLines("new", 2500))
+	if !errors.Is(err, ErrDiffTooLarge) {
+		t.Fatalf("err = %v, want ErrDiffTooLarge", err)
+	}
+}
+
+func TestDiffLinesSkipsCommonEndsBeforeTheCap(t *testing.T) {
+	// Long documents with a small edit stay well under the cap because the
+	// shared leading and trailing lines never enter the table.
+	head := numberedLines("head", 5000)
+	tail := numberedLines("tail", 5000)
+	got, err := DiffLines(head+"\nold\n"+tail, head+"\nnew\n"+tail)
+	if err != nil {
+		t.Fatalf("DiffLines: %v", err)
+	}
+
+	changed := 0
+	for _, line := range got {
+		if line.Op != DiffEqual {
+			changed++
+		}
+	}
+	if len(got) != 10002 || changed != 2 {
+		t.Fatalf("%d lines with %d changes, want 10002 with 2", len(got), changed)
+	}
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gogogo/config"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const oidcStateAudience = "oidc_state"
+
+// OIDCState is everything the callback needs to finish a sign-in started by
+// this browser. It travels signed in a short-lived HttpOnly cookie, so the
+// server keeps no per-login storage.
+type OIDCState struct {
+	ID       string
+	Provider string
+	Nonce    string
+	Verifier string
+	Redirect string
+}
+
+type oidcStateClaims struct {
+	jwt.StandardClaims
+	Provider string `json:"provider"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	Redirect string `json:"redirect,omitempty"`
+}
+
+func GenerateOIDCState(state OIDCState, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := &oidcStateClaims{
+		StandardClaims: jwt.StandardClaims{
+			Audience:  oidcStateAudience,
+			ExpiresAt: now.Add(ttl).Unix(),
+			Id:        state.ID,
+			IssuedAt:  now.Unix(),
+			Issuer:    config.AppConfig.App.Name,
+		},
+		Provider: state.Provider,
+		Nonce:    state.Nonce,
+		Verifier: state.Verifier,
+		Redirect: state.Redirect,
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(config.AppConfig.Auth.JWTSecret))
+}
+
+func ValidateOIDCState(tokenString string) (*OIDCState, error) {
+	parsed, err := jwt.ParseWithClaims(tokenString, &oidcStateClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(config.AppConfig.Auth.JWTSecret), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := parsed.Claims.(*oidcStateClaims)
+	if !ok || !parsed.Valid || !claims.VerifyAudience(oidcStateAudience, true) {
+		return nil, errors.New("invalid state")
+	}
+
+	return &OIDCState{
+		ID:       claims.Id,
+		Provider: claims.Provider,
+		Nonce:    claims.Nonce,
+		Verifier: claims.Verifier,
+		Redirect: claims.Redirect,
+	}, nil
+}
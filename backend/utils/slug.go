@@ -3,16 +3,97 @@ package utils
 import (
 	"regexp"
 	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gogogo/config"
+
+	"github.com/gosimple/unidecode"
+)
+
+const (
+	SlugModeTransliterate = "transliterate"
+	SlugModeUnicode       = "unicode"
+
+	// maxSlugBytes fits the narrowest slug column (tags and categories).
+	maxSlugBytes = 64
 )
 
 var slugPattern = regexp.MustCompile(`[^a-z0-9]+`)
 
+// Slugify turns text into a URL slug. By default it transliterates to ASCII,
+// which spells Han characters in pinyin and strips Latin accents. With
+// slug.mode set to "unicode", letters of any script are kept as they are and
+// end up percent-encoded in URLs; transliteration also falls back to that
+// when it produces nothing, e.g. for emoji.
 func Slugify(value string) string {
-	slug := strings.ToLower(value)
-	slug = slugPattern.ReplaceAllString(slug, "-")
-	slug = strings.Trim(slug, "-")
+	slug := ""
+	if slugMode() != SlugModeUnicode {
+		slug = asciiSlug(unidecode.Unidecode(value))
+	}
+	if slug == "" {
+		slug = unicodeSlug(value)
+	}
+	if slug == "" {
+		return "item"
+	}
+	return truncateSlug(slug)
+}
+
+// LegacySlugify reproduces the original ASCII-only slugs. It is only used to
+// recognise slugs generated before transliteration existed.
+func LegacySlugify(value string) string {
+	slug := asciiSlug(value)
 	if slug == "" {
 		return "item"
 	}
 	return slug
 }
+
+func slugMode() string {
+	if config.AppConfig == nil {
+		return SlugModeTransliterate
+	}
+	return strings.ToLower(config.AppConfig.Slug.Mode)
+}
+
+func asciiSlug(value string) string {
+	slug := strings.ToLower(value)
+	slug = slugPattern.ReplaceAllString(slug, "-")
+	return strings.Trim(slug, "-")
+}
+
+func unicodeSlug(value string) string {
+	var builder strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(value) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.Is(unicode.Mn, r) {
+			if dash && builder.Len() > 0 {
+				builder.WriteByte('-')
+			}
+			builder.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	return builder.String()
+}
+
+// truncateSlug cuts at a word boundary where possible and never splits a
+// multi-byte character.
+func truncateSlug(slug string) string {
+	if len(slug) <= maxSlugBytes {
+		return slug
+	}
+
+	cut := maxSlugBytes
+	for cut > 0 && !utf8.RuneStart(slug[cut]) {
+		cut--
+	}
+	truncated := slug[:cut]
+	if i := strings.LastIndexByte(truncated, '-'); i > maxSlugBytes/2 {
+		truncated = truncated[:i]
+	}
+	return strings.Trim(truncated, "-")
+}
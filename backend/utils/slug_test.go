@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"gogogo/config"
+)
+
+func setSlugMode(t *testing.T, mode string) {
+	t.Helper()
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{}
+	config.AppConfig.Slug.Mode = mode
+	t.Cleanup(func() { config.AppConfig = previous })
+}
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		mode  string
+		value string
+		want  string
+	}{
+		{SlugModeTransliterate, "Hello, World!", "hello-world"},
+		{SlugModeTransliterate, "  --Go 1.22 released--  ", "go-1-22-released"},
+		{SlugModeTransliterate, "Crème Brûlée", "creme-brulee"},
+		{SlugModeTransliterate, "你好世界", "ni-hao-shi-jie"},
+		{SlugModeTransliterate, "🎉🎉", "item"},
+		{SlugModeTransliterate, "", "item"},
+		{SlugModeUnicode, "你好 世界", "你好-世界"},
+		{SlugModeUnicode, "Crème Brûlée", "crème-brûlée"},
+		{SlugModeUnicode, "Hello, World!", "hello-world"},
+		{SlugModeUnicode, "!!!", "item"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode+"/"+tt.value, func(t *testing.T) {
+			setSlugMode(t, tt.mode)
+			if got := Slugify(tt.value); got != tt.want {
+				t.Fatalf("Slugify(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlugifyTruncatesAtAWordBoundary(t *testing.T) {
+	setSlugMode(t, SlugModeTransliterate)
+
+	// The 64th byte falls inside the eleventh word, which is dropped.
+	got := Slugify(strings.Repeat("words ", 20))
+	if want := strings.Repeat("words-", 9) + "words"; got != want {
+		t.Fatalf("Slugify = %q, want %q", got, want)
+	}
+}
+
+func TestSlugifyNeverSplitsACharacter(t *testing.T) {
+	setSlugMode(t, SlugModeUnicode)
+
+	got := Slugify(strings.Repeat("文", 30))
+	if len(got) > maxSlugBytes || !utf8.ValidString(got) || got != strings.Repeat("文", maxSlugBytes/3) {
+		t.Fatalf("Slugify = %q (%d bytes), want 21 whole characters", got, len(got))
+	}
+}
+
+func TestLegacySlugify(t *testing.T) {
+	if got := LegacySlugify("Crème Brûlée"); got != "cr-me-br-l-e" {
+		t.Fatalf("LegacySlugify = %q, want the old ASCII-only slug", got)
+	}
+	if got := LegacySlugify("你好"); got != "item" {
+		t.Fatalf("LegacySlugify = %q, want item", got)
+	}
+}
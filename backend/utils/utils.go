@@ -23,23 +23,40 @@ func CheckPassword(password string, hash string) bool {
 }
 
 type JWTClaims struct {
-	UserID   uint   `json:"sub"`
-	Username string `json:"username"`
+	UserID    uint   `json:"sub"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	SessionID uint   `json:"sid"`
 	jwt.StandardClaims
 }
 
-// 生产JWT根据用户ID和用户名
-func GenerateJWT(userID uint, username string) (string, error) {
-	ttl := config.AppConfig.Auth.TokenTTLHours
+// AccessTokenTTL returns the configured lifetime of access tokens.
+func AccessTokenTTL() time.Duration {
+	ttl := config.AppConfig.Auth.AccessTokenTTLMinutes
 	if ttl <= 0 {
-		ttl = 72
+		ttl = 15
 	}
+	return time.Duration(ttl) * time.Minute
+}
+
+// RefreshTokenTTL returns the configured lifetime of refresh tokens.
+func RefreshTokenTTL() time.Duration {
+	ttl := config.AppConfig.Auth.RefreshTokenTTLHours
+	if ttl <= 0 {
+		ttl = 720
+	}
+	return time.Duration(ttl) * time.Hour
+}
 
+// 生产JWT根据用户ID、用户名、角色和会话ID
+func GenerateJWT(userID uint, username string, role string, sessionID uint) (string, error) {
 	claims := &JWTClaims{
-		UserID:   userID,
-		Username: username,
+		UserID:    userID,
+		Username:  username,
+		Role:      role,
+		SessionID: sessionID,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Duration(ttl) * time.Hour).Unix(),
+			ExpiresAt: time.Now().Add(AccessTokenTTL()).Unix(),
 			IssuedAt:  time.Now().Unix(),
 			Subject:   strconv.FormatUint(uint64(userID), 10),
 			Issuer:    config.AppConfig.App.Name,
@@ -62,7 +79,8 @@ func ValidateJWT(tokenString string) (*JWTClaims, error) {
 	}
 
 	claims, ok := parsed.Claims.(*JWTClaims)
-	if !ok || !parsed.Valid {
+	// Action tokens share the signing key but always carry an audience.
+	if !ok || !parsed.Valid || claims.Audience != "" {
 		return nil, errors.New("invalid token")
 	}
 
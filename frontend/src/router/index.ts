@@ -1,12 +1,19 @@
 import { createRouter, createWebHistory } from 'vue-router'
 import HomePage from '@/pages/HomePage.vue'
 import PostDetailPage from '@/pages/PostDetailPage.vue'
+import PostPreviewPage from '@/pages/PostPreviewPage.vue'
 import LoginPage from '@/pages/LoginPage.vue'
 import RegisterPage from '@/pages/RegisterPage.vue'
+import AuthCallbackPage from '@/pages/AuthCallbackPage.vue'
+import ResetPasswordPage from '@/pages/ResetPasswordPage.vue'
+import VerifyEmailPage from '@/pages/VerifyEmailPage.vue'
 import DashboardLayout from '@/pages/dashboard/DashboardLayout.vue'
 import DashboardPostsPage from '@/pages/dashboard/DashboardPostsPage.vue'
 import DashboardCategoriesPage from '@/pages/dashboard/DashboardCategoriesPage.vue'
 import DashboardTagsPage from '@/pages/dashboard/DashboardTagsPage.vue'
+import DashboardTrashPage from '@/pages/dashboard/DashboardTrashPage.vue'
+import DashboardReviewsPage from '@/pages/dashboard/DashboardReviewsPage.vue'
+import DashboardCommentsPage from '@/pages/dashboard/DashboardCommentsPage.vue'
 import { useAuthStore } from '@/store/auth'
 
 const router = createRouter({
@@ -23,6 +30,11 @@ const router = createRouter({
       component: PostDetailPage,
       props: true,
     },
+    {
+      path: '/preview',
+      name: 'post-preview',
+      component: PostPreviewPage,
+    },
     {
       path: '/login',
       name: 'login',
@@ -35,6 +47,21 @@ const router = createRouter({
       component: RegisterPage,
       meta: { guestOnly: true },
     },
+    {
+      path: '/reset-password',
+      name: 'reset-password',
+      component: ResetPasswordPage,
+    },
+    {
+      path: '/verify-email',
+      name: 'verify-email',
+      component: VerifyEmailPage,
+    },
+    {
+      path: '/auth/callback',
+      name: 'auth-callback',
+      component: AuthCallbackPage,
+    },
     {
       path: '/dashboard',
       component: DashboardLayout,
@@ -59,6 +86,22 @@ const router = createRouter({
           name: 'dashboard-tags',
           component: DashboardTagsPage,
         },
+        {
+          path: 'trash',
+          name: 'dashboard-trash',
+          component: DashboardTrashPage,
+        },
+        {
+          path: 'comments',
+          name: 'dashboard-comments',
+          component: DashboardCommentsPage,
+        },
+        {
+          path: 'reviews',
+          name: 'dashboard-reviews',
+          component: DashboardReviewsPage,
+          meta: { requiresReviewer: true },
+        },
       ],
     },
   ],
@@ -75,6 +118,10 @@ router.beforeEach(async (to) => {
     }
   }
 
+  if (to.meta.requiresReviewer && !auth.canReview) {
+    return { name: 'dashboard-posts' }
+  }
+
   if (to.meta.guestOnly && auth.isAuthenticated) {
     return { name: 'home' }
   }
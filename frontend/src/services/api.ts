@@ -1,12 +1,18 @@
 import axios from 'axios'
+import type { AxiosError, InternalAxiosRequestConfig } from 'axios'
+
+export const TOKEN_STORAGE_KEY = 'auth_token'
+export const REFRESH_TOKEN_STORAGE_KEY = 'refresh_token'
+
+const baseURL = import.meta.env.VITE_API_BASE_URL ?? '/api'
 
 const api = axios.create({
-  baseURL: import.meta.env.VITE_API_BASE_URL ?? '/api',
+  baseURL,
   timeout: 10000,
 })
 
 api.interceptors.request.use((config) => {
-  const token = localStorage.getItem('auth_token')
+  const token = localStorage.getItem(TOKEN_STORAGE_KEY)
   if (token) {
     config.headers = config.headers ?? {}
     config.headers.Authorization = `Bearer ${token}`
@@ -14,4 +20,51 @@ api.interceptors.request.use((config) => {
   return config
 })
 
+let refreshing: Promise<string | null> | null = null
+
+// Access tokens are short-lived; trade the refresh token for a new pair once
+// and share the result between requests that failed concurrently.
+const refreshAccessToken = async (): Promise<string | null> => {
+  const refreshToken = localStorage.getItem(REFRESH_TOKEN_STORAGE_KEY)
+  if (!refreshToken) return null
+
+  try {
+    const { data } = await axios.post<{ token: string; refreshToken: string }>(
+      `${baseURL}/auth/refresh`,
+      { refreshToken },
+    )
+    localStorage.setItem(TOKEN_STORAGE_KEY, data.token)
+    localStorage.setItem(REFRESH_TOKEN_STORAGE_KEY, data.refreshToken)
+    return data.token
+  } catch {
+    localStorage.removeItem(TOKEN_STORAGE_KEY)
+    localStorage.removeItem(REFRESH_TOKEN_STORAGE_KEY)
+    return null
+  }
+}
+
+api.interceptors.response.use(undefined, async (error: AxiosError) => {
+  const original = error.config as
+    | (InternalAxiosRequestConfig & { _retried?: boolean })
+    | undefined
+  if (
+    error.response?.status !== 401 ||
+    !original ||
+    original._retried ||
+    original.url?.startsWith('/auth/')
+  ) {
+    throw error
+  }
+
+  refreshing = refreshing ?? refreshAccessToken().finally(() => {
+    refreshing = null
+  })
+  const token = await refreshing
+  if (!token) throw error
+
+  original._retried = true
+  original.headers.Authorization = `Bearer ${token}`
+  return api(original)
+})
+
 export default api
@@ -1,16 +1,33 @@
 import api from './api'
-import type { User } from '@/types'
+import type { OidcProvider, User } from '@/types'
 
 export interface AuthResponse {
   token: string
+  refreshToken: string
+  expiresAt: string
   user: User
 }
 
+export interface MfaChallenge {
+  mfaRequired: true
+  challengeToken: string
+  expiresAt: string
+}
+
 export const login = async (payload: {
   username: string
   password: string
+}): Promise<AuthResponse | MfaChallenge> => {
+  const { data } = await api.post<AuthResponse | MfaChallenge>('/auth/login', payload)
+  return data
+}
+
+export const verifyMfa = async (payload: {
+  challengeToken: string
+  code?: string
+  recoveryCode?: string
 }): Promise<AuthResponse> => {
-  const { data } = await api.post<AuthResponse>('/auth/login', payload)
+  const { data } = await api.post<AuthResponse>('/auth/mfa', payload)
   return data
 }
 
@@ -24,6 +41,36 @@ export const register = async (payload: {
   return data
 }
 
+export const logout = async (refreshToken: string | null): Promise<void> => {
+  await api.post('/auth/logout', refreshToken ? { refreshToken } : undefined)
+}
+
+export const forgotPassword = async (email: string): Promise<void> => {
+  await api.post('/auth/forgot-password', { email })
+}
+
+export const resetPassword = async (payload: {
+  token: string
+  newPassword: string
+}): Promise<void> => {
+  await api.post('/auth/reset-password', payload)
+}
+
+export const verifyEmail = async (token: string): Promise<void> => {
+  await api.get('/auth/verify-email', { params: { token } })
+}
+
+export const fetchOidcProviders = async (): Promise<OidcProvider[]> => {
+  const { data } = await api.get<{ data: OidcProvider[] }>('/auth/oidc/providers')
+  return data.data
+}
+
+// The OIDC flow is a full-page redirect, so this is a link rather than a request.
+export const oidcLoginUrl = (provider: string, redirect: string): string => {
+  const base = (api.defaults.baseURL ?? '/api').replace(/\/$/, '')
+  return `${base}/auth/oidc/${encodeURIComponent(provider)}/login?redirect=${encodeURIComponent(redirect)}`
+}
+
 export const fetchProfile = async (): Promise<User> => {
   const { data } = await api.get<{ user: User }>('/me')
   return data.user
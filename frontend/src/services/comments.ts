@@ -1,22 +1,74 @@
 import api from './api'
-import type { Comment } from '@/types'
+import { postAccessHeaders } from './posts'
+import type {
+  Comment,
+  CommentPage,
+  CommentRepliesPage,
+  CommentSort,
+  CommentStatus,
+  ModerationAction,
+  ModerationComment,
+  Paginated,
+} from '@/types'
 
 export const fetchComments = async (
   postIdOrSlug: number | string,
-): Promise<Comment[]> => {
-  const { data } = await api.get<{ data: Comment[] }>(
-    `/posts/${postIdOrSlug}/comments`,
+  params: { sort?: CommentSort; cursor?: string; pageSize?: number } = {},
+): Promise<CommentPage> => {
+  const { data } = await api.get<CommentPage>(`/posts/${postIdOrSlug}/comments`, {
+    params,
+    headers: typeof postIdOrSlug === 'number' ? postAccessHeaders(postIdOrSlug) : {},
+  })
+  return data
+}
+
+export const fetchCommentReplies = async (
+  postId: number,
+  commentId: number,
+  cursor: string,
+): Promise<CommentRepliesPage> => {
+  const { data } = await api.get<CommentRepliesPage>(
+    `/posts/${postId}/comments/${commentId}/replies`,
+    { params: { cursor }, headers: postAccessHeaders(postId) },
   )
-  return data.data
+  return data
 }
 
 export const createComment = async (
   postIdOrSlug: number | string,
-  payload: { authorName?: string; body: string },
+  payload: { authorName?: string; body: string; parentId?: number },
 ): Promise<Comment> => {
   const { data } = await api.post<{ data: Comment }>(
     `/posts/${postIdOrSlug}/comments`,
     payload,
+    { headers: typeof postIdOrSlug === 'number' ? postAccessHeaders(postIdOrSlug) : {} },
   )
   return data.data
 }
+
+export const updateComment = async (id: number, body: string): Promise<Comment> => {
+  const { data } = await api.put<{ data: Comment }>(`/comments/${id}`, { body })
+  return data.data
+}
+
+export const deleteComment = async (id: number): Promise<void> => {
+  await api.delete(`/comments/${id}`)
+}
+
+export const fetchModerationComments = async (
+  params: { status?: CommentStatus; postId?: number; page?: number; pageSize?: number } = {},
+): Promise<Paginated<ModerationComment>> => {
+  const { data } = await api.get<Paginated<ModerationComment>>('/moderation/comments', { params })
+  return data
+}
+
+export const moderateComments = async (
+  ids: number[],
+  action: ModerationAction,
+): Promise<{ action: ModerationAction; count: number }> => {
+  const { data } = await api.post<{ action: ModerationAction; count: number }>(
+    '/moderation/comments',
+    { ids, action },
+  )
+  return data
+}
@@ -1,5 +1,16 @@
 import api from './api'
-import type { Paginated, Post, PostInput } from '@/types'
+import type {
+  Paginated,
+  Post,
+  PostInput,
+  PostPreview,
+  PostPreviewView,
+  PostRevision,
+  PostTransition,
+  ReviewQueueItem,
+  RevisionDiff,
+  TrashedPost,
+} from '@/types'
 
 export interface PostQuery {
   page?: number
@@ -19,13 +30,35 @@ export const fetchPosts = async (
   return data
 }
 
+const UNLOCK_STORAGE_PREFIX = 'post_unlock:'
+
+// Unlock tokens for password-protected posts last for the browser session and
+// are sent as X-Post-Token on reads of that post and its comments.
+export const postAccessHeaders = (id: number): Record<string, string> => {
+  const token = sessionStorage.getItem(`${UNLOCK_STORAGE_PREFIX}${id}`)
+  return token ? { 'X-Post-Token': token } : {}
+}
+
+export const unlockPost = async (id: number, password: string): Promise<void> => {
+  const { data } = await api.post<{ token: string; expiresAt: string }>(`/posts/${id}/unlock`, {
+    password,
+  })
+  sessionStorage.setItem(`${UNLOCK_STORAGE_PREFIX}${id}`, data.token)
+}
+
 export const fetchPostBySlug = async (slug: string): Promise<Post> => {
   const { data } = await api.get<{ data: Post }>(`/posts/slug/${slug}`)
+  // The id is only known after the first read; retry with a saved unlock token.
+  if (data.data.locked && Object.keys(postAccessHeaders(data.data.id)).length) {
+    return fetchPostById(data.data.id)
+  }
   return data.data
 }
 
 export const fetchPostById = async (id: number): Promise<Post> => {
-  const { data } = await api.get<{ data: Post }>(`/posts/${id}`)
+  const { data } = await api.get<{ data: Post }>(`/posts/${id}`, {
+    headers: postAccessHeaders(id),
+  })
   return data.data
 }
 
@@ -52,3 +85,115 @@ export const updatePost = async (
 export const deletePost = async (id: number): Promise<void> => {
   await api.delete(`/posts/${id}`)
 }
+
+export const fetchTrash = async (
+  params: { page?: number; pageSize?: number } = {},
+): Promise<Paginated<TrashedPost>> => {
+  const { data } = await api.get<Paginated<TrashedPost>>('/me/trash', { params })
+  return data
+}
+
+export const restorePost = async (id: number): Promise<Post> => {
+  const { data } = await api.post<{ data: Post }>(`/posts/${id}/restore`)
+  return data.data
+}
+
+export const purgePost = async (id: number): Promise<void> => {
+  await api.delete(`/posts/${id}/purge`)
+}
+
+export const submitPostForReview = async (id: number, notes?: string): Promise<Post> => {
+  const { data } = await api.post<{ data: Post }>(`/posts/${id}/submit`, { notes })
+  return data.data
+}
+
+export const reviewPost = async (
+  id: number,
+  payload: { decision: 'approve' | 'request_changes'; notes?: string },
+): Promise<Post> => {
+  const { data } = await api.post<{ data: Post }>(`/posts/${id}/review`, payload)
+  return data.data
+}
+
+export const fetchPostTransitions = async (id: number): Promise<PostTransition[]> => {
+  const { data } = await api.get<{ data: PostTransition[] }>(`/posts/${id}/transitions`)
+  return data.data
+}
+
+export const fetchReviewQueue = async (
+  params: { page?: number; pageSize?: number } = {},
+): Promise<Paginated<ReviewQueueItem>> => {
+  const { data } = await api.get<Paginated<ReviewQueueItem>>('/review-queue', { params })
+  return data
+}
+
+export const fetchPostRevisions = async (
+  id: number,
+  params: { page?: number; pageSize?: number } = {},
+): Promise<Paginated<PostRevision>> => {
+  const { data } = await api.get<Paginated<PostRevision>>(`/posts/${id}/revisions`, { params })
+  return data
+}
+
+export const fetchPostRevision = async (id: number, revision: number): Promise<PostRevision> => {
+  const { data } = await api.get<{ data: PostRevision }>(`/posts/${id}/revisions/${revision}`)
+  return data.data
+}
+
+export const diffPostRevisions = async (
+  id: number,
+  from: number,
+  to?: number,
+): Promise<RevisionDiff> => {
+  const { data } = await api.get<RevisionDiff>(`/posts/${id}/revisions/diff`, {
+    params: { from, to },
+  })
+  return data
+}
+
+export const restorePostRevision = async (id: number, revision: number): Promise<Post> => {
+  const { data } = await api.post<{ data: Post }>(`/posts/${id}/revisions/${revision}/restore`)
+  return data.data
+}
+
+export const fetchPostPreviews = async (id: number): Promise<PostPreview[]> => {
+  const { data } = await api.get<{ data: PostPreview[] }>(`/posts/${id}/previews`)
+  return data.data
+}
+
+// The preview link is only returned here; the server keeps no copy of it.
+export const createPostPreview = async (
+  id: number,
+  payload: { label?: string; expiresInHours?: number },
+): Promise<{ preview: PostPreview; url: string }> => {
+  const { data } = await api.post<{ data: PostPreview; token: string; url: string }>(
+    `/posts/${id}/previews`,
+    payload,
+  )
+  return { preview: data.data, url: data.url }
+}
+
+export const revokePostPreview = async (id: number, previewId: number): Promise<void> => {
+  await api.delete(`/posts/${id}/previews/${previewId}`)
+}
+
+export const fetchPostPreviewViews = async (
+  id: number,
+  previewId: number,
+  params: { page?: number; pageSize?: number } = {},
+): Promise<Paginated<PostPreviewView>> => {
+  const { data } = await api.get<Paginated<PostPreviewView>>(
+    `/posts/${id}/previews/${previewId}/views`,
+    { params },
+  )
+  return data
+}
+
+export const fetchPostPreview = async (
+  token: string,
+): Promise<{ post: Post; expiresAt: string }> => {
+  const { data } = await api.get<{ data: Post; expiresAt: string }>('/preview', {
+    params: { token },
+  })
+  return { post: data.data, expiresAt: data.expiresAt }
+}
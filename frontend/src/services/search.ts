@@ -0,0 +1,16 @@
+import api from './api'
+import type { SearchResponse } from '@/types'
+
+export interface SearchQuery {
+  q: string
+  page?: number
+  pageSize?: number
+  category?: string
+  tag?: string
+  author?: string
+}
+
+export const searchPosts = async (params: SearchQuery): Promise<SearchResponse> => {
+  const { data } = await api.get<SearchResponse>('/search', { params })
+  return data
+}
@@ -2,8 +2,7 @@ import { defineStore } from 'pinia'
 import { computed, ref } from 'vue'
 import type { User } from '@/types'
 import * as authService from '@/services/auth'
-
-const TOKEN_STORAGE_KEY = 'auth_token'
+import { REFRESH_TOKEN_STORAGE_KEY, TOKEN_STORAGE_KEY } from '@/services/api'
 
 export const useAuthStore = defineStore('auth', () => {
   const user = ref<User | null>(null)
@@ -11,10 +10,18 @@ export const useAuthStore = defineStore('auth', () => {
   const isInitialized = ref(false)
   const isProcessing = ref(false)
   const error = ref<string | null>(null)
+  const mfaChallenge = ref<string | null>(null)
 
   const isAuthenticated = computed(() => Boolean(user.value && token.value))
+  // Mirrors the server's posts.publish and posts.edit_others permissions.
+  const canPublish = computed(() => ['author', 'editor', 'admin'].includes(user.value?.role ?? ''))
+  const canReview = computed(() => ['editor', 'admin'].includes(user.value?.role ?? ''))
 
-  const setSession = (authToken: string | null, profile: User | null) => {
+  const setSession = (
+    authToken: string | null,
+    profile: User | null,
+    refreshToken: string | null = null,
+  ) => {
     token.value = authToken
     user.value = profile
     if (authToken) {
@@ -22,6 +29,11 @@ export const useAuthStore = defineStore('auth', () => {
     } else {
       localStorage.removeItem(TOKEN_STORAGE_KEY)
     }
+    if (refreshToken) {
+      localStorage.setItem(REFRESH_TOKEN_STORAGE_KEY, refreshToken)
+    } else if (!authToken) {
+      localStorage.removeItem(REFRESH_TOKEN_STORAGE_KEY)
+    }
   }
 
   const initialize = async () => {
@@ -53,7 +65,11 @@ export const useAuthStore = defineStore('auth', () => {
     error.value = null
     try {
       const response = await authService.login(payload)
-      setSession(response.token, response.user)
+      if ('mfaRequired' in response) {
+        mfaChallenge.value = response.challengeToken
+        return null
+      }
+      setSession(response.token, response.user, response.refreshToken)
       return response.user
     } catch (err) {
       error.value = 'Login failed'
@@ -63,6 +79,60 @@ export const useAuthStore = defineStore('auth', () => {
     }
   }
 
+  const verifyMfa = async (payload: { code?: string; recoveryCode?: string }) => {
+    if (!mfaChallenge.value) {
+      throw new Error('No pending sign-in challenge')
+    }
+    isProcessing.value = true
+    error.value = null
+    try {
+      const response = await authService.verifyMfa({
+        challengeToken: mfaChallenge.value,
+        ...payload,
+      })
+      mfaChallenge.value = null
+      setSession(response.token, response.user, response.refreshToken)
+      return response.user
+    } catch (err) {
+      error.value = 'Verification failed'
+      throw err
+    } finally {
+      isProcessing.value = false
+    }
+  }
+
+  const cancelMfa = () => {
+    mfaChallenge.value = null
+  }
+
+  // completeOidcLogin consumes the fragment the backend redirects to after an
+  // identity provider sign-in. Returns false when a second factor is needed.
+  const completeOidcLogin = async (params: URLSearchParams) => {
+    const failure = params.get('error')
+    if (failure) {
+      throw new Error(failure)
+    }
+
+    const challenge = params.get('mfaChallenge')
+    if (challenge) {
+      mfaChallenge.value = challenge
+      return false
+    }
+
+    const authToken = params.get('token')
+    if (!authToken) {
+      throw new Error('missing_token')
+    }
+    setSession(authToken, null, params.get('refreshToken'))
+    try {
+      user.value = await authService.fetchProfile()
+    } catch (err) {
+      setSession(null, null)
+      throw err
+    }
+    return true
+  }
+
   const register = async (payload: {
     username: string
     password: string
@@ -73,7 +143,7 @@ export const useAuthStore = defineStore('auth', () => {
     error.value = null
     try {
       const response = await authService.register(payload)
-      setSession(response.token, response.user)
+      setSession(response.token, response.user, response.refreshToken)
       return response.user
     } catch (err) {
       error.value = 'Register failed'
@@ -84,7 +154,13 @@ export const useAuthStore = defineStore('auth', () => {
   }
 
   const logout = async () => {
-    setSession(null, null)
+    try {
+      await authService.logout(localStorage.getItem(REFRESH_TOKEN_STORAGE_KEY))
+    } catch (err) {
+      console.error('Failed to revoke session', err)
+    } finally {
+      setSession(null, null)
+    }
   }
 
   const refreshProfile = async () => {
@@ -105,8 +181,14 @@ export const useAuthStore = defineStore('auth', () => {
     isInitialized,
     error,
     isAuthenticated,
+    canPublish,
+    canReview,
+    mfaChallenge,
     initialize,
     login,
+    verifyMfa,
+    cancelMfa,
+    completeOidcLogin,
     register,
     logout,
     refreshProfile,
@@ -2,12 +2,22 @@ export interface User {
   id: number
   username: string
   email?: string
+  role?: 'admin' | 'editor' | 'author' | 'contributor' | 'reader'
+  mfaEnabled?: boolean
+  suspendedAt?: string
+  suspendedReason?: string
   displayName: string
   bio?: string
   avatarUrl?: string
   createdAt: string
 }
 
+export interface OidcProvider {
+  name: string
+  displayName: string
+  loginUrl: string
+}
+
 export interface Category {
   id: number
   name: string
@@ -23,32 +33,161 @@ export interface Tag {
   createdAt: string
 }
 
+export type CommentStatus = 'pending' | 'approved' | 'rejected' | 'spam'
+
 export interface Comment {
   id: number
   authorName: string
   body: string
   approved: boolean
+  status: CommentStatus
+  parentId: number | null
+  rootId: number | null
+  depth: number
+  replyCount: number
+  // Deleted comments that still have replies come back without author or body.
+  deleted: boolean
+  editedAt?: string
   createdAt: string
   user?: User
+  // Set on a top-level comment whose thread has more replies to load.
+  repliesCursor?: string
+}
+
+export type CommentSort = 'oldest' | 'newest' | 'top'
+
+// Comments are paged by thread; pass nextCursor back to get the next page.
+export interface CommentPage {
+  data: Comment[]
+  nextCursor: string | null
+  total: number
+}
+
+export interface CommentRepliesPage {
+  data: Comment[]
+  nextCursor: string | null
+}
+
+export interface ModerationComment extends Comment {
+  post: { id: number; title: string; slug: string }
+  ipAddress: string
+  userAgent: string
+  spamScore: number
+  spamReasons: string[]
+  previousBody?: string
+}
+
+export type ModerationAction = 'approve' | 'reject' | 'spam' | 'delete'
+
+export interface TocEntry {
+  level: number
+  text: string
+  id: string
 }
 
+export type PostStatus =
+  | 'draft'
+  | 'pending_review'
+  | 'changes_requested'
+  | 'approved'
+  | 'scheduled'
+  | 'published'
+  | 'archived'
+
+export type PostVisibility = 'public' | 'unlisted' | 'private' | 'password'
+
 export interface Post {
   id: number
   title: string
   summary?: string
   content: string
+  contentHtml?: string
+  toc?: TocEntry[]
   slug: string
-  status: 'draft' | 'published' | 'archived'
+  status: PostStatus
+  visibility: PostVisibility
+  locked?: boolean
   coverImage?: string
   publishedAt?: string | null
   author: User
   category?: Category | null
   tags: Tag[]
-  comments?: Comment[]
+  // Only set on listings and post pages, and never for locked posts.
+  commentCount?: number
   createdAt: string
   updatedAt: string
 }
 
+export interface PostRevision {
+  number: number
+  title: string
+  summary: string
+  content?: string
+  slug: string
+  status: string
+  coverImage?: string
+  categoryId?: number
+  categoryName?: string
+  tags: string[]
+  restoredFrom?: number
+  editor: User
+  createdAt: string
+}
+
+export interface TrashedPost extends Post {
+  deletedAt: string
+  purgeAt?: string
+}
+
+export interface PostTransition {
+  id: number
+  from: PostStatus | ''
+  to: PostStatus
+  notes?: string
+  actor?: User
+  createdAt: string
+}
+
+export interface ReviewQueueItem {
+  post: Post
+  submittedAt: string
+  notes?: string
+}
+
+export interface PostPreview {
+  id: number
+  label: string
+  createdBy: User
+  expiresAt: string
+  revokedAt?: string
+  active: boolean
+  viewCount: number
+  lastViewedAt?: string
+  createdAt: string
+}
+
+export interface PostPreviewView {
+  id: number
+  ipAddress: string
+  userAgent: string
+  viewedAt: string
+}
+
+export interface DiffLine {
+  op: 'equal' | 'insert' | 'delete'
+  text: string
+  oldLine?: number
+  newLine?: number
+}
+
+export interface RevisionDiff {
+  from: PostRevision
+  to: PostRevision
+  changes: Record<string, { from: unknown; to: unknown }>
+  content: DiffLine[]
+  stats: { added: number; removed: number }
+}
+
 export interface Paginated<T> {
   data: T[]
   page: number
@@ -56,11 +195,38 @@ export interface Paginated<T> {
   total: number
 }
 
+export interface SearchHighlights {
+  title: string
+  summary: string
+  content: string
+}
+
+export interface SearchResult {
+  post: Post
+  score: number
+  highlights: SearchHighlights
+}
+
+export interface SearchFacet {
+  slug: string
+  name: string
+  count: number
+}
+
+export interface SearchResponse extends Paginated<SearchResult> {
+  facets: {
+    categories: SearchFacet[]
+    tags: SearchFacet[]
+  }
+}
+
 export interface PostInput {
   title: string
   summary?: string
   content: string
   status: string
+  visibility?: PostVisibility
+  password?: string
   slug?: string
   categoryId?: number | null
   categorySlug?: string